@@ -0,0 +1,18 @@
+package lafzi
+
+// ConfusionMatrix is the cost of substituting a phonetic rune with another
+// rune that often confused with it, e.g. ha and ain that written as "h" and
+// "x" in the phonetic. The cost is between 0 (the same) and 1 (completely
+// different). The matrix is symmetric, so each pair only needs to be listed
+// once, and the pairs that not listed are completely different. The runes
+// are the phonetic runes that described in package phonetic.
+type ConfusionMatrix map[[2]rune]float64
+
+// DefaultConfusionMatrix is the pairs of consonants that often confused by
+// the users, since they sound similar for non-native speakers.
+var DefaultConfusionMatrix = ConfusionMatrix{
+	{'h', 'x'}: 0.5, // ha and ain
+	{'d', 't'}: 0.5, // dal and ta
+	{'k', 'g'}: 0.5, // qaf and ghain
+	{'s', 'z'}: 0.5, // sin and zay
+}
@@ -0,0 +1,174 @@
+package lafzi
+
+import (
+	"context"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+// Explanation is the breakdown of why a document matches the query and how
+// it's scored, which is useful to diagnose an unexpected search result.
+type Explanation struct {
+	Query      string
+	Identifier string
+	Text       string
+
+	// HasPositions is true if the document has matching token positions
+	// whose confidence reaches the minimum confidence, which are listed in
+	// Positions along with their best Confidence. They are the same as the
+	// ones in Result, however the document might still be left out by the
+	// search, e.g. if it's outside the Limit and Offset, beyond the
+	// ShortQueryLimit, or removed while re-ranked.
+	HasPositions bool
+	Confidence   float64
+	Positions    [][2]int
+
+	// Queries is the phonetic queries that created from the query. Each of
+	// them is scored separately, then the best confidence is used.
+	Queries []QueryExplanation
+}
+
+// QueryExplanation explains how a single phonetic query is matched in the
+// document. Beside the phonetic of the query, the query is also searched
+// using its consonant skeleton to find the document without harakat.
+type QueryExplanation struct {
+	// Kind is the kind of the phonetic query, e.g. "regular" for the
+	// phonetic and "skeleton" for the consonant skeleton.
+	Kind string
+
+	// Normalized is the normalized query, which split into Trigrams.
+	Normalized string
+	Trigrams   []string
+
+	// Matches is the locations of the trigrams in the document.
+	Matches []TrigramMatch
+
+	// Groups is the group of consecutive matches along with their scores.
+	Groups []GroupExplanation
+}
+
+// TrigramMatch is the location of a query trigram in the document.
+type TrigramMatch struct {
+	// Index is the index of the trigram in QueryExplanation.Trigrams.
+	Index   int
+	Trigram string
+	Start   int
+	End     int
+
+	// Cost is the substitution cost if the trigram is matched using the
+	// confused runes, or zero if it's matched exactly.
+	Cost float64
+}
+
+// GroupExplanation is the group of consecutive trigram matches, which
+// scored by its completeness (how many trigrams are matched) and compactness
+// (how close the matches to each other). Only the group whose confidence
+// reach the minimum confidence is used in the search result.
+type GroupExplanation struct {
+	Start        int
+	End          int
+	Count        int
+	Positions    []int
+	Completeness float64
+	Compactness  float64
+	Confidence   float64
+	Matched      bool
+}
+
+// Explain explains how the document with the specified identifier is
+// matched and scored using the specified query.
+func (st *Storage) Explain(query, identifier string) (Explanation, error) {
+	return st.ExplainContext(context.Background(), query, identifier)
+}
+
+// ExplainContext explains how the document with the specified identifier is
+// matched and scored, using the context to cancel the process if needed.
+func (st *Storage) ExplainContext(ctx context.Context, query, identifier string) (Explanation, error) {
+	return st.ExplainWithOptions(ctx, query, identifier, st.defaultSearchOptions())
+}
+
+// ExplainWithOptions explains how the document with the specified identifier
+// is matched and scored by the search with the same query and options. If the
+// document doesn't exist, ErrNotFound will be returned.
+func (st *Storage) ExplainWithOptions(ctx context.Context, query, identifier string, opts SearchOptions) (Explanation, error) {
+	// Make sure storage is still open
+	if err := st.acquire(); err != nil {
+		return Explanation{}, err
+	}
+	defer st.mu.RUnlock()
+
+	// Convert query to n-gram tokens
+	queries, indexOpts := prepareSearch(query, opts, st.idx.SkeletonTokens())
+
+	// Find the document and explain its tokens in a single view
+	var doc index.Document
+	var exp index.Explanation
+	err := st.idx.View(ctx, func(r index.Reader) error {
+		var found bool
+		var err error
+		doc, found, err = r.FindDocument(ctx, identifier)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return ErrNotFound
+		}
+
+		exp, err = index.ExplainTokens(ctx, r, indexOpts, doc.ID, queries...)
+		return err
+	})
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	// Create the final explanation
+	explanation := Explanation{
+		Query:        query,
+		Identifier:   doc.Identifier,
+		Text:         doc.Arabic,
+		HasPositions: len(exp.Positions) > 0,
+		Confidence:   exp.Confidence,
+		Positions:    exp.Positions,
+		Queries:      make([]QueryExplanation, len(queries)),
+	}
+
+	for i, q := range queries {
+		qe := QueryExplanation{
+			Kind:       q.Kind.String(),
+			Normalized: q.Text,
+			Trigrams:   make([]string, len(q.Tokens)),
+		}
+
+		for j, token := range q.Tokens {
+			_, qe.Trigrams[j] = index.DecodeToken(token)
+		}
+
+		for _, tl := range exp.Locations[i] {
+			qe.Matches = append(qe.Matches, TrigramMatch{
+				Index:   tl.TokenID,
+				Trigram: qe.Trigrams[tl.TokenID],
+				Start:   tl.Start,
+				End:     tl.End,
+				Cost:    tl.Cost,
+			})
+		}
+
+		for _, g := range exp.Groups[i] {
+			qe.Groups = append(qe.Groups, GroupExplanation{
+				Start:        g.Start,
+				End:          g.End,
+				Count:        g.Count,
+				Positions:    g.Positions,
+				Completeness: g.Completeness,
+				Compactness:  g.Compactness,
+				Confidence:   g.Confidence,
+				Matched:      g.Confidence >= indexOpts.MinConfidence,
+			})
+		}
+
+		explanation.Queries[i] = qe
+	}
+
+	return explanation, nil
+}
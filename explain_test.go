@@ -0,0 +1,116 @@
+package lafzi
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	// Every document shares trigrams with the query, so the locations
+	// in the other documents must not leak into the explanation
+	docs := []Document{
+		{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"},
+		{Identifier: "samad", Arabic: "اللَّهُ الصَّمَدُ"},
+		{Identifier: "basmalah", Arabic: "بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ"},
+	}
+
+	tests := []struct {
+		query         string
+		identifier    string
+		wantPositions bool
+	}{
+		{"qul huwallahu ahad", "ikhlas", true},
+		{"qul huwallahu ahad", "samad", false},
+		{"allahussamad", "samad", true},
+		{"allahussamad", "ikhlas", false},
+		{"bismillahirrahmanirrahim", "basmalah", true},
+		{"bismillahirrahmanirrahim", "ikhlas", false},
+		{"la", "basmalah", true},
+	}
+
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			// Replace a document, so its tokens are no longer in order
+			if err := st.AddDocuments(docs...); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := st.AddDocuments(docs[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				exp, err := st.Explain(tt.query, tt.identifier)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if exp.HasPositions != tt.wantPositions {
+					t.Errorf("explain %q in %s: got has positions %v, want %v",
+						tt.query, tt.identifier, exp.HasPositions, tt.wantPositions)
+				}
+
+				// The explanation must agree with the search result
+				results, err := st.Search(tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				idx := slices.IndexFunc(results, func(r Result) bool {
+					return r.Identifier == tt.identifier
+				})
+
+				if found := idx >= 0; found != exp.HasPositions {
+					t.Errorf("explain %q in %s: got has positions %v, but found by search %v",
+						tt.query, tt.identifier, exp.HasPositions, found)
+				} else if found {
+					r := results[idx]
+					if r.Confidence != exp.Confidence || !slices.Equal(r.Positions, exp.Positions) {
+						t.Errorf("explain %q in %s: got %v %v, want %v %v", tt.query, tt.identifier,
+							exp.Confidence, exp.Positions, r.Confidence, r.Positions)
+					}
+				}
+
+				// Each match must be located within the document
+				nRunes := len([]rune(exp.Text))
+				for _, q := range exp.Queries {
+					for _, m := range q.Matches {
+						if m.Start < 0 || m.End > nRunes || m.Start >= m.End {
+							t.Errorf("explain %q in %s: match %+v is out of the text",
+								tt.query, tt.identifier, m)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExplainNotFound(t *testing.T) {
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			err := st.AddDocuments(Document{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := st.DeleteDocuments("ikhlas"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, identifier := range []string{"ikhlas", "missing"} {
+				_, err := st.Explain("qul huwallahu ahad", identifier)
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("explain %s: got error %v, want %v", identifier, err, ErrNotFound)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package align
+
+// Scoring is the score that given to each operation in the alignment.
+// Match should be positive, while Mismatch and Gap should be negative.
+type Scoring[T comparable] struct {
+	Match    float64
+	Mismatch float64
+	Gap      float64
+
+	// SubstitutionCost returns the cost of substituting an element with a
+	// different one, between 0 (scored as Match) and 1 (scored as Mismatch).
+	// If it's nil, every substitution is scored as Mismatch.
+	SubstitutionCost func(a, b T) float64
+}
+
+// substitute returns the score of aligning a with b.
+func (s Scoring[T]) substitute(a, b T) float64 {
+	switch {
+	case a == b:
+		return s.Match
+	case s.SubstitutionCost == nil:
+		return s.Mismatch
+	default:
+		cost := min(max(s.SubstitutionCost(a, b), 0), 1)
+		return s.Match - cost*(s.Match-s.Mismatch)
+	}
+}
+
+// Alignment is the best local alignment between two sequences. The aligned
+// part of each sequence is in [Start, End) range, where End is exclusive.
+type Alignment struct {
+	Score  float64
+	AStart int
+	AEnd   int
+	BStart int
+	BEnd   int
+}
+
+// cell is a single cell in the alignment matrix, along with
+// the start of the alignment that ends in this cell.
+type cell struct {
+	score  float64
+	aStart int
+	bStart int
+}
+
+// Local returns the best local alignment between a and b using the
+// Smith-Waterman algorithm. Instead of keeping the whole matrix for the
+// traceback, each cell keeps the start of its alignment, so it only requires
+// O(len(b)) space and O(len(a) * len(b)) time. If there are several best
+// alignments, the one that ends first is returned.
+func Local[T comparable](a, b []T, scoring Scoring[T]) Alignment {
+	var best Alignment
+	prev := make([]cell, len(b)+1)
+	curr := make([]cell, len(b)+1)
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			// The score never goes below zero, which means the alignment
+			// is restarted from the next cell.
+			var c cell
+
+			// Extend the alignment diagonally, either match or substitution.
+			// If the previous cell is restarted, the alignment starts here.
+			diagonal := prev[j-1].score + scoring.substitute(a[i-1], b[j-1])
+
+			if diagonal > c.score {
+				c = cell{diagonal, prev[j-1].aStart, prev[j-1].bStart}
+				if prev[j-1].score <= 0 {
+					c.aStart, c.bStart = i-1, j-1
+				}
+			}
+
+			// Extend the alignment with a gap in b or in a
+			if up := prev[j].score + scoring.Gap; up > c.score {
+				c = cell{up, prev[j].aStart, prev[j].bStart}
+			}
+
+			if left := curr[j-1].score + scoring.Gap; left > c.score {
+				c = cell{left, curr[j-1].aStart, curr[j-1].bStart}
+			}
+
+			curr[j] = c
+			if c.score > best.Score {
+				best = Alignment{
+					Score:  c.score,
+					AStart: c.aStart,
+					AEnd:   i,
+					BStart: c.bStart,
+					BEnd:   j,
+				}
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return best
+}
@@ -0,0 +1,42 @@
+package align
+
+import "testing"
+
+func TestLocal(t *testing.T) {
+	scoring := Scoring[rune]{Match: 1, Mismatch: -1, Gap: -1}
+
+	// h and x are often confused, so substituting them only costs a quarter
+	confused := scoring
+	confused.SubstitutionCost = func(a, b rune) float64 {
+		if (a == 'h' && b == 'x') || (a == 'x' && b == 'h') {
+			return 0.25
+		}
+		return 1
+	}
+
+	tests := []struct {
+		name    string
+		a, b    string
+		scoring Scoring[rune]
+		want    Alignment
+	}{
+		{"exact", "abc", "abc", scoring, Alignment{3, 0, 3, 0, 3}},
+		{"exact within", "abc", "xxabcxx", scoring, Alignment{3, 0, 3, 2, 5}},
+		{"gap in a", "abcd", "abxcd", scoring, Alignment{3, 0, 4, 0, 5}},
+		{"gap in b", "abxcd", "abcd", scoring, Alignment{3, 0, 5, 0, 4}},
+		{"mismatch keeps the first best", "abcd", "abxd", scoring, Alignment{2, 0, 2, 0, 2}},
+		{"unconfused", "hamd", "xamd", scoring, Alignment{3, 1, 4, 1, 4}},
+		{"confused", "hamd", "xamd", confused, Alignment{3.5, 0, 4, 0, 4}},
+		{"nothing in common", "abc", "xyz", scoring, Alignment{}},
+		{"empty a", "", "abc", scoring, Alignment{}},
+		{"empty b", "abc", "", scoring, Alignment{}},
+		{"both empty", "", "", scoring, Alignment{}},
+	}
+
+	for _, tt := range tests {
+		got := Local([]rune(tt.a), []rune(tt.b), tt.scoring)
+		if got != tt.want {
+			t.Errorf("%s: Local(%q, %q) = %+v, want %+v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,20 +1,21 @@
 package database
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 )
 
 // DeleteDocuments remove documents in database.
-func DeleteDocuments(db *sqlx.DB, identifiers ...string) (err error) {
+func (db *DB) DeleteDocuments(ctx context.Context, identifiers ...string) (err error) {
 	// If there are no identifiers submitted, stop early
 	if len(identifiers) == 0 {
 		return nil
 	}
 
 	// Start transaction
-	tx, err := db.Beginx()
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
 		err = fmt.Errorf("failed to start transaction: %v", err)
 		return
@@ -27,6 +28,20 @@ func DeleteDocuments(db *sqlx.DB, identifiers ...string) (err error) {
 		}
 	}()
 
+	// In postings layout, the postings must be removed manually. Meanwhile in
+	// rows layout the tokens are removed by cascade, however the frequencies
+	// must be updated manually.
+	switch db.Layout {
+	case LayoutPostings:
+		err = deleteDocumentPostings(ctx, tx, identifiers, db.skeletonTokens)
+	default:
+		err = deleteDocumentFrequencies(ctx, tx, identifiers)
+	}
+
+	if err != nil {
+		return
+	}
+
 	// Prepare query
 	sqlDoc, docArgs, err := sqlx.In(`
 		DELETE FROM document
@@ -36,7 +51,7 @@ func DeleteDocuments(db *sqlx.DB, identifiers ...string) (err error) {
 	}
 
 	// Execute query
-	_, err = tx.Exec(sqlDoc, docArgs...)
+	_, err = tx.ExecContext(ctx, sqlDoc, docArgs...)
 	if err != nil {
 		return
 	}
@@ -45,3 +60,56 @@ func DeleteDocuments(db *sqlx.DB, identifiers ...string) (err error) {
 	err = tx.Commit()
 	return
 }
+
+// deleteDocumentPostings remove the postings of the documents
+// from the posting lists.
+func deleteDocumentPostings(ctx context.Context, tx *sqlx.Tx, identifiers []string, skeletonTokens bool) (err error) {
+	// Fetch the document IDs
+	query, args, err := sqlx.In(`
+		SELECT id FROM document
+		WHERE identifier IN (?)`, identifiers)
+	if err != nil {
+		return
+	}
+
+	var documentIDs []int
+	err = tx.SelectContext(ctx, &documentIDs, tx.Rebind(query), args...)
+	if err != nil || len(documentIDs) == 0 {
+		return
+	}
+
+	// Find the tokens of the documents, then remove their postings
+	removed, err := documentTokenCodes(ctx, tx, documentIDs, skeletonTokens)
+	if err != nil {
+		return
+	}
+
+	return updatePostings(ctx, tx, removed, nil)
+}
+
+// deleteDocumentFrequencies decreases the frequency of tokens
+// that used by the documents.
+func deleteDocumentFrequencies(ctx context.Context, tx *sqlx.Tx, identifiers []string) (err error) {
+	query, args, err := sqlx.In(`
+		SELECT t.token, COUNT(DISTINCT t.document_id) frequency
+		FROM document_token t
+		JOIN document d ON d.id = t.document_id
+		WHERE d.identifier IN (?)
+		GROUP BY t.token`, identifiers)
+	if err != nil {
+		return
+	}
+
+	var frequencies []TokenFrequency
+	err = tx.SelectContext(ctx, &frequencies, tx.Rebind(query), args...)
+	if err != nil || len(frequencies) == 0 {
+		return
+	}
+
+	deltas := make(map[int64]int, len(frequencies))
+	for _, tf := range frequencies {
+		deltas[tf.Token] = -tf.Frequency
+	}
+
+	return updateTokenFrequencies(ctx, tx, deltas)
+}
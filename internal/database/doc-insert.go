@@ -1,34 +1,32 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 
-	"github.com/hablullah/go-lafzi/internal/phonetic"
+	"github.com/hablullah/go-lafzi/internal/index"
 	"github.com/jmoiron/sqlx"
 )
 
-type InsertDocumentArg struct {
-	Identifier string
-	Arabic     string
-	Phonetic   phonetic.Group
-}
-
 // InsertDocuments save the documents into the database.
-func InsertDocuments(db *sqlx.DB, args ...InsertDocumentArg) (err error) {
+func (db *DB) InsertDocuments(ctx context.Context, args ...index.InsertDocumentArg) (err error) {
 	// If there are no args submitted, stop early
 	if len(args) == 0 {
 		return nil
 	}
 
-	// Remove index, and create it once it over
-	_, err = db.Exec(`DROP INDEX IF EXISTS document_token_idx_token`)
-	if err != nil {
-		return
+	// In rows layout, remove index and create it once it over
+	if db.Layout == LayoutRows {
+		_, err = db.ExecContext(ctx, `DROP INDEX IF EXISTS document_token_idx_token`)
+		if err != nil {
+			return
+		}
 	}
 
 	// Start transaction
-	tx, err := db.Beginx()
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
 		err = fmt.Errorf("failed to start transaction: %v", err)
 		return
@@ -40,40 +38,49 @@ func InsertDocuments(db *sqlx.DB, args ...InsertDocumentArg) (err error) {
 			tx.Rollback()
 		}
 
-		// Recreate index
-		if err == nil {
-			_, err = db.Exec(ddlCreateDocumentTokenIndexToken)
+		// Recreate index. Since the index is dropped before, it must be
+		// recreated even when the context is already cancelled.
+		if db.Layout == LayoutRows {
+			_, errIndex := db.ExecContext(context.WithoutCancel(ctx), ddlCreateDocumentTokenIndexToken)
+			if err == nil {
+				err = errIndex
+			}
 		}
 	}()
 
 	// Prepare statement
-	stmtGetDoc, err := tx.Preparex(`SELECT id FROM document WHERE identifier = ?`)
+	stmtGetDoc, err := tx.PreparexContext(ctx, `SELECT id FROM document WHERE identifier = ?`)
 	if err != nil {
 		return
 	}
 
-	stmtInsertDoc, err := tx.Preparex(`
-		INSERT INTO document (identifier, arabic)
-		VALUES (?, ?)
+	stmtInsertDoc, err := tx.PreparexContext(ctx, `
+		INSERT INTO document (identifier, arabic, unvocalized)
+		VALUES (?, ?, ?)
 		ON CONFLICT (identifier) DO UPDATE
-		SET arabic = excluded.arabic`)
+		SET arabic = excluded.arabic,
+			unvocalized = excluded.unvocalized`)
 	if err != nil {
 		return
 	}
 
-	stmtDeleteDocToken, err := tx.Preparex(`
-		DELETE FROM document_token
-		WHERE document_id = ?`)
+	savePhonetic, err := phoneticWriter(ctx, tx)
 	if err != nil {
 		return
 	}
 
-	stmtInsertDocToken, err := tx.Preparex(`
-		INSERT INTO document_token (document_id, token, start, end)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT DO NOTHING`)
-	if err != nil {
-		return
+	// Prepare the token writer for the current layout
+	var saveTokens func(documentID int64, exist bool, tokens []index.Token) error
+	var finishTokens func() error
+
+	switch db.Layout {
+	case LayoutPostings:
+		saveTokens, finishTokens = postingsTokenWriter(ctx, tx, db.skeletonTokens)
+	default:
+		saveTokens, finishTokens, err = rowsTokenWriter(ctx, tx)
+		if err != nil {
+			return
+		}
 	}
 
 	// Insert the document
@@ -81,7 +88,7 @@ func InsertDocuments(db *sqlx.DB, args ...InsertDocumentArg) (err error) {
 		// Get document ID if it's exist
 		var documentID int64
 		documentExist := true
-		err = stmtGetDoc.Get(&documentID, arg.Identifier)
+		err = stmtGetDoc.GetContext(ctx, &documentID, arg.Identifier)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				documentExist = false
@@ -91,11 +98,22 @@ func InsertDocuments(db *sqlx.DB, args ...InsertDocumentArg) (err error) {
 			}
 		}
 
+		// The old tokens of the existing document are found using its saved
+		// phonetic and flag, so its tokens are saved before it's replaced.
+		tokens := index.DocumentTokens(arg, db.skeletonTokens)
+		if documentExist {
+			err = saveTokens(documentID, true, tokens)
+			if err != nil {
+				return
+			}
+		}
+
 		// Save document
 		var res sql.Result
-		res, err = stmtInsertDoc.Exec(
+		res, err = stmtInsertDoc.ExecContext(ctx,
 			arg.Identifier,
-			arg.Arabic)
+			arg.Arabic,
+			arg.Unvocalized)
 		if err != nil {
 			return
 		}
@@ -106,28 +124,183 @@ func InsertDocuments(db *sqlx.DB, args ...InsertDocumentArg) (err error) {
 			if err != nil {
 				return
 			}
+
+			err = saveTokens(documentID, false, tokens)
+			if err != nil {
+				return
+			}
 		}
 
-		// Remove any token that associated with this document
-		_, err = stmtDeleteDocToken.Exec(documentID)
+		// Save phonetic
+		err = savePhonetic(documentID, arg)
 		if err != nil {
 			return
 		}
+	}
+
+	if finishTokens != nil {
+		err = finishTokens()
+		if err != nil {
+			return
+		}
+	}
+
+	// Commit to database
+	err = tx.Commit()
+	return
+}
+
+// phoneticWriter returns function to save the phonetic and skeleton of a
+// document in table `document_phonetic`, replacing the old one if exists.
+func phoneticWriter(ctx context.Context, tx *sqlx.Tx) (func(int64, index.InsertDocumentArg) error, error) {
+	stmt, err := tx.PreparexContext(ctx, `
+		INSERT INTO document_phonetic (document_id, phonetic, skeleton)
+		VALUES (?, ?, ?)
+		ON CONFLICT (document_id) DO UPDATE
+		SET phonetic = excluded.phonetic,
+			skeleton = excluded.skeleton`)
+	if err != nil {
+		return nil, err
+	}
+
+	save := func(documentID int64, arg index.InsertDocumentArg) error {
+		_, err := stmt.ExecContext(ctx,
+			documentID,
+			index.EncodePhonetic(arg.Phonetic),
+			index.EncodePhonetic(arg.Skeleton))
+		return err
+	}
+
+	return save, nil
+}
+
+// rowsTokenWriter returns functions to save the document tokens as rows in
+// table `document_token`, then update the token frequencies once all
+// documents has been saved.
+func rowsTokenWriter(ctx context.Context, tx *sqlx.Tx) (func(int64, bool, []index.Token) error, func() error, error) {
+	stmtSelectDocToken, err := tx.PreparexContext(ctx, `
+		SELECT DISTINCT token FROM document_token
+		WHERE document_id = ?`)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stmtDeleteDocToken, err := tx.PreparexContext(ctx, `
+		DELETE FROM document_token
+		WHERE document_id = ?`)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stmtInsertDocToken, err := tx.PreparexContext(ctx, `
+		INSERT INTO document_token (document_id, token, start, end)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	frequencyDeltas := make(map[int64]int)
+	save := func(documentID int64, exist bool, tokens []index.Token) error {
+		// Remove any token that associated with this document
+		if exist {
+			var oldTokens []int64
+			err := stmtSelectDocToken.SelectContext(ctx, &oldTokens, documentID)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+
+			for _, token := range oldTokens {
+				frequencyDeltas[token]--
+			}
+
+			_, err = stmtDeleteDocToken.ExecContext(ctx, documentID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Count each distinct token once
+		for _, code := range distinctTokenCodes(tokens) {
+			frequencyDeltas[code]++
+		}
 
 		// Save tokens
-		for _, token := range arg.Phonetic.Split(3) {
-			_, err = stmtInsertDocToken.Exec(
+		for _, token := range tokens {
+			_, err := stmtInsertDocToken.ExecContext(ctx,
 				documentID,
-				token.Text,
+				token.Code,
 				token.Start,
 				token.End)
 			if err != nil {
-				return
+				return err
 			}
 		}
+
+		return nil
 	}
 
-	// Commit to database
-	err = tx.Commit()
-	return
+	finish := func() error {
+		return updateTokenFrequencies(ctx, tx, frequencyDeltas)
+	}
+
+	return save, finish, nil
+}
+
+// distinctTokenCodes returns the distinct code of the tokens.
+func distinctTokenCodes(tokens []index.Token) []int64 {
+	codes := make([]int64, len(tokens))
+	for i, token := range tokens {
+		codes[i] = token.Code
+	}
+
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+// postingsTokenWriter returns functions to collect the document tokens, then
+// save them as posting lists in table `token_posting` once all documents
+// has been collected. This way each block of posting list only updated once. The
+// tokens must be saved before the existing document and its phonetic are
+// replaced, since they are used to find the old tokens.
+func postingsTokenWriter(ctx context.Context, tx *sqlx.Tx, skeletonTokens bool) (func(int64, bool, []index.Token) error, func() error) {
+	removed := make(map[int64][]int)
+	documentTokens := make(map[int][]index.Token)
+
+	save := func(documentID int64, exist bool, tokens []index.Token) error {
+		// Old postings of the existing document must be removed. If the same
+		// document submitted twice, the latter one replaces the former, and
+		// its old tokens are already collected.
+		_, collected := documentTokens[int(documentID)]
+		if exist && !collected {
+			codes, err := documentTokenCodes(ctx, tx, []int{int(documentID)}, skeletonTokens)
+			if err != nil {
+				return err
+			}
+
+			for token, ids := range codes {
+				removed[token] = append(removed[token], ids...)
+			}
+		}
+
+		documentTokens[int(documentID)] = tokens
+		return nil
+	}
+
+	finish := func() error {
+		added := make(map[int64][]index.Posting)
+		for documentID, tokens := range documentTokens {
+			for _, token := range tokens {
+				added[token.Code] = append(added[token.Code], index.Posting{
+					DocumentID: documentID,
+					Start:      token.Start,
+					End:        token.End,
+				})
+			}
+		}
+
+		return updatePostings(ctx, tx, removed, added)
+	}
+
+	return save, finish
 }
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+// BenchmarkInsertIncremental measures inserting the documents one by one,
+// where the posting lists of common tokens keep growing. Each layout is
+// measured using several number of documents, so the cost of each insert
+// that grows along with the index can be seen.
+func BenchmarkInsertIncremental(b *testing.B) {
+	texts := []string{
+		"قُلْ هُوَ اللَّهُ أَحَدٌ",
+		"اللَّهُ الصَّمَدُ",
+		"بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ",
+		"الْحَمْدُ لِلَّهِ رَبِّ الْعَالَمِينَ",
+		"قُلْ أَعُوذُ بِرَبِّ النَّاسِ",
+	}
+
+	for _, nDocument := range []int{500, 2000} {
+		args := make([]index.InsertDocumentArg, nDocument)
+		for i := range args {
+			identifier := fmt.Sprintf("doc-%d", i)
+			args[i] = index.NewInsertDocumentArg(identifier, texts[i%len(texts)], false)
+		}
+
+		for _, layout := range []Layout{LayoutRows, LayoutPostings} {
+			b.Run(fmt.Sprintf("%s/%d", layout, nDocument), func(b *testing.B) {
+				ctx := context.Background()
+				for b.Loop() {
+					b.StopTimer()
+					db, err := Open(ctx, filepath.Join(b.TempDir(), "lafzi.db"), layout, true)
+					if err != nil {
+						b.Fatal(err)
+					}
+					b.StartTimer()
+
+					for _, arg := range args {
+						if err := db.InsertDocuments(ctx, arg); err != nil {
+							b.Fatal(err)
+						}
+					}
+
+					b.StopTimer()
+					db.Close()
+					b.StartTimer()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type TokenFrequency struct {
+	Token     int64 `db:"token"`
+	Frequency int   `db:"frequency"`
+}
+
+// TokenFrequencies fetch the number of documents that contain each token,
+// along with the number of all documents.
+func (r reader) TokenFrequencies(ctx context.Context, tokens []int64) (frequencies map[int64]int, nDocument int, err error) {
+	err = r.tx.GetContext(ctx, &nDocument, `SELECT COUNT(*) FROM document`)
+	if err != nil {
+		return
+	}
+
+	frequencies = make(map[int64]int, len(tokens))
+	for batch := range slices.Chunk(tokens, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT token, frequency FROM token_frequency
+			WHERE token IN (?)`, batch)
+		if err != nil {
+			return
+		}
+
+		var batchFrequencies []TokenFrequency
+		err = r.tx.SelectContext(ctx, &batchFrequencies, r.tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, tf := range batchFrequencies {
+			frequencies[tf.Token] = tf.Frequency
+		}
+	}
+
+	return frequencies, nDocument, nil
+}
+
+// updateTokenFrequencies adds the deltas into the number of documents that
+// contain each token, then removes the tokens that no longer used.
+func updateTokenFrequencies(ctx context.Context, tx *sqlx.Tx, deltas map[int64]int) (err error) {
+	stmtUpdate, err := tx.PreparexContext(ctx, `
+		INSERT INTO token_frequency (token, frequency)
+		VALUES (?, ?)
+		ON CONFLICT (token) DO UPDATE
+		SET frequency = frequency + excluded.frequency`)
+	if err != nil {
+		return
+	}
+
+	for token, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+
+		_, err = stmtUpdate.ExecContext(ctx, token, delta)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM token_frequency WHERE frequency <= 0`)
+	return
+}
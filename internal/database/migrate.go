@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/jmoiron/sqlx"
+)
+
+// schemaVersion is the version of the current database schema. It's saved
+// in `user_version` pragma, so old database can be migrated when opened.
+// The released database before the schema is versioned has version 0.
+const schemaVersion = 1
+
+// migrate creates the tables with the latest schema, or upgrades the old
+// tables into the latest schema. Since the old tables are indexed again,
+// the requested layout and skeleton tokens are used for them as well.
+// Returns true if the old tables are upgraded.
+func migrate(ctx context.Context, tx *sqlx.Tx, layout Layout, skeletonTokens bool) (migrated bool, err error) {
+	// Check the current schema version
+	var version int
+	err = tx.GetContext(ctx, &version, `PRAGMA user_version`)
+	if err != nil {
+		return
+	}
+
+	if version > schemaVersion {
+		err = fmt.Errorf("database schema version %d is newer than supported version %d",
+			version, schemaVersion)
+		return
+	}
+
+	// If the tables already exist without version, migrate them
+	var nTable int
+	err = tx.GetContext(ctx, &nTable, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name = 'document'`)
+	if err != nil {
+		return
+	}
+
+	if nTable > 0 && version < schemaVersion {
+		err = migrateUnversioned(ctx, tx, layout, skeletonTokens)
+		if err != nil {
+			err = fmt.Errorf("failed to migrate schema to version %d: %v", schemaVersion, err)
+			return
+		}
+		migrated = true
+	}
+
+	// Generate tables
+	ddlQueries := []string{
+		ddlCreateDocument,
+		ddlCreateDocumentToken,
+		ddlCreateDocumentTokenIndexToken,
+		ddlCreateTokenPosting,
+		ddlCreateTokenFrequency,
+		ddlCreateDocumentPhonetic,
+		ddlCreateMetadata,
+		fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion)}
+
+	for _, query := range ddlQueries {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// migrateUnversioned upgrades the tables from the released version before
+// the schema is versioned, which only has table `document` and the trigrams
+// in `document_token` as text. Every document in that version is vocalized,
+// so they are simply indexed again like the new documents.
+func migrateUnversioned(ctx context.Context, tx *sqlx.Tx, layout Layout, skeletonTokens bool) (err error) {
+	if layout == LayoutDefault {
+		layout = LayoutRows
+	}
+
+	// Replace the old tokens and create the new tables
+	ddlQueries := []string{
+		`ALTER TABLE document ADD COLUMN unvocalized INTEGER NOT NULL DEFAULT 0`,
+		`DROP INDEX IF EXISTS document_token_idx_token`,
+		`DROP TABLE IF EXISTS document_token`,
+		ddlCreateDocumentToken,
+		ddlCreateTokenPosting,
+		ddlCreateTokenFrequency,
+		ddlCreateDocumentPhonetic,
+		ddlCreateMetadata}
+
+	for _, query := range ddlQueries {
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return
+		}
+	}
+
+	// Save the layout and skeleton tokens that used to index the documents
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO metadata (key, value)
+		VALUES ('layout', ?), ('skeleton_tokens', ?)`,
+		layout.String(), strconv.FormatBool(skeletonTokens))
+	if err != nil {
+		return
+	}
+
+	// Prepare the writers for phonetic and tokens
+	savePhonetic, err := phoneticWriter(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	var saveTokens func(documentID int64, exist bool, tokens []index.Token) error
+	var finishTokens func() error
+
+	switch layout {
+	case LayoutPostings:
+		saveTokens, finishTokens = postingsTokenWriter(ctx, tx, skeletonTokens)
+	default:
+		saveTokens, finishTokens, err = rowsTokenWriter(ctx, tx)
+		if err != nil {
+			return
+		}
+	}
+
+	// Index each document
+	var documents []Document
+	err = tx.SelectContext(ctx, &documents, `SELECT id, identifier, arabic FROM document`)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range documents {
+		arg := index.NewInsertDocumentArg(doc.Identifier, doc.Arabic, false)
+		err = saveTokens(int64(doc.ID), false, index.DocumentTokens(arg, skeletonTokens))
+		if err != nil {
+			return
+		}
+
+		err = savePhonetic(int64(doc.ID), arg)
+		if err != nil {
+			return
+		}
+	}
+
+	if finishTokens != nil {
+		err = finishTokens()
+	}
+
+	return
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func TestMigrate(t *testing.T) {
+	// The released version before the schema is versioned
+	// only knows the vocalized documents.
+	args := []index.InsertDocumentArg{
+		index.NewInsertDocumentArg("la", "لَا", false),
+		index.NewInsertDocumentArg("ikhlas", "قُلْ هُوَ اللَّهُ أَحَدٌ", false),
+		index.NewInsertDocumentArg("basmalah", "بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ", false),
+	}
+
+	for _, layout := range []Layout{LayoutDefault, LayoutRows, LayoutPostings} {
+		for _, skeletonTokens := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s-%v", layout, skeletonTokens), func(t *testing.T) {
+				ctx := context.Background()
+				path := filepath.Join(t.TempDir(), "lafzi.db")
+				if err := createUnversionedDatabase(ctx, path, args); err != nil {
+					t.Fatal(err)
+				}
+
+				// Open the database, which migrates it to the latest schema
+				db, err := Open(ctx, path, layout, skeletonTokens)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer db.Close()
+
+				var version int
+				if err := db.GetContext(ctx, &version, `PRAGMA user_version`); err != nil {
+					t.Fatal(err)
+				}
+
+				if version != schemaVersion {
+					t.Errorf("got schema version %d, want %d", version, schemaVersion)
+				}
+
+				wantLayout := layout
+				if wantLayout == LayoutDefault {
+					wantLayout = LayoutRows
+				}
+
+				if db.Layout != wantLayout {
+					t.Errorf("got layout %s, want %s", db.Layout, wantLayout)
+				}
+
+				if db.SkeletonTokens() != skeletonTokens {
+					t.Errorf("got skeleton tokens %v, want %v", db.SkeletonTokens(), skeletonTokens)
+				}
+
+				checkMigratedDocuments(t, db, args)
+			})
+		}
+	}
+}
+
+// createUnversionedDatabase creates the database using the schema of the
+// released version before the schema is versioned, where the tokens are
+// saved as text.
+func createUnversionedDatabase(ctx context.Context, path string, args []index.InsertDocumentArg) error {
+	db, err := sqlx.ConnectContext(ctx, "sqlite", "file:"+path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ddlQueries := []string{`
+		CREATE TABLE document (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			identifier TEXT    UNIQUE NOT NULL,
+			arabic     TEXT    NOT NULL,
+			UNIQUE (identifier))`, `
+		CREATE TABLE document_token (
+			document_id INTEGER NOT NULL,
+			token       TEXT    NOT NULL,
+			start       INTEGER NOT NULL,
+			end         INTEGER NOT NULL,
+			CONSTRAINT token_document_fk
+				FOREIGN KEY (document_id)
+				REFERENCES document (id)
+				ON DELETE CASCADE)`,
+		`CREATE INDEX document_token_idx_token ON document_token (token)`}
+
+	for _, query := range ddlQueries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	for _, arg := range args {
+		res, err := db.ExecContext(ctx, `
+			INSERT INTO document (identifier, arabic)
+			VALUES (?, ?)`, arg.Identifier, arg.Arabic)
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for _, ngram := range arg.Phonetic.Split(3) {
+			_, err = db.ExecContext(ctx, `
+				INSERT INTO document_token (document_id, token, start, end)
+				VALUES (?, ?, ?, ?)`, id, ngram.Text, ngram.Start, ngram.End)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkMigratedDocuments makes sure the flag, phonetic and tokens of the
+// migrated documents are the same as the newly inserted ones.
+func checkMigratedDocuments(t *testing.T, db *DB, args []index.InsertDocumentArg) {
+	t.Helper()
+	ctx := context.Background()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	r := reader{tx, db.Layout}
+
+	// The skeleton tokens are looked up as well, so they
+	// must be missing if they are not enabled.
+	var codes []int64
+	for _, arg := range args {
+		for _, token := range index.DocumentTokens(arg, true) {
+			codes = append(codes, token.Code)
+		}
+	}
+
+	wantFrequencies := make(map[int64]int)
+	for _, arg := range args {
+		var doc Document
+		err := tx.GetContext(ctx, &doc, `
+			SELECT id, identifier, arabic, unvocalized
+			FROM document WHERE identifier = ?`, arg.Identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.Unvocalized != arg.Unvocalized {
+			t.Errorf("%s: got unvocalized %v, want %v",
+				arg.Identifier, doc.Unvocalized, arg.Unvocalized)
+		}
+
+		phonetics, err := r.DocumentPhonetics(ctx, []int{doc.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dp := phonetics[doc.ID]; !slices.Equal(dp.Phonetic, arg.Phonetic) {
+			t.Errorf("%s: got phonetic %q, want %q",
+				arg.Identifier, dp.Phonetic.String(), arg.Phonetic.String())
+		}
+
+		// The document must only have the tokens that used by new document
+		var wantLocations []index.TokenLocation
+		docCodes := make(map[int64]struct{})
+		for _, token := range index.DocumentTokens(arg, db.SkeletonTokens()) {
+			docCodes[token.Code] = struct{}{}
+			wantLocations = append(wantLocations, index.TokenLocation{
+				DocumentID: doc.ID,
+				Token:      token.Code,
+				Start:      token.Start,
+				End:        token.End,
+			})
+		}
+
+		for code := range docCodes {
+			wantFrequencies[code]++
+		}
+
+		locations, err := r.LookupDocumentTokens(ctx, doc.ID, codes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sortLocations(locations)
+		sortLocations(wantLocations)
+		if !slices.Equal(locations, wantLocations) {
+			t.Errorf("%s: got %d token locations, want %d",
+				arg.Identifier, len(locations), len(wantLocations))
+		}
+	}
+
+	frequencies, _, err := r.TokenFrequencies(ctx, codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !maps.Equal(frequencies, wantFrequencies) {
+		t.Errorf("got %d token frequencies, want %d", len(frequencies), len(wantFrequencies))
+	}
+}
+
+func sortLocations(locations []index.TokenLocation) {
+	slices.SortFunc(locations, func(a, b index.TokenLocation) int {
+		return cmp.Or(
+			cmp.Compare(a.Token, b.Token),
+			cmp.Compare(a.Start, b.Start),
+			cmp.Compare(a.End, b.End))
+	})
+}
+
+func TestInsertUnvocalized(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "lafzi.db"), LayoutDefault, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The flag must follow the latest document with the same identifier
+	for _, unvocalized := range []bool{true, false, true} {
+		arg := index.NewInsertDocumentArg("la", "لا", unvocalized)
+		if err := db.InsertDocuments(ctx, arg); err != nil {
+			t.Fatal(err)
+		}
+
+		var got bool
+		err := db.GetContext(ctx, &got, `SELECT unvocalized FROM document WHERE identifier = ?`, "la")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != unvocalized {
+			t.Errorf("got unvocalized %v, want %v", got, unvocalized)
+		}
+	}
+}
+
+func TestOpenSkeletonTokens(t *testing.T) {
+	ctx := context.Background()
+	for _, skeletonTokens := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "lafzi.db")
+		db, err := Open(ctx, path, LayoutDefault, skeletonTokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		// The existing database keeps its own setting, whatever requested
+		for _, requested := range []bool{false, true} {
+			db, err = Open(ctx, path, LayoutDefault, requested)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if db.SkeletonTokens() != skeletonTokens {
+				t.Errorf("reopen with %v: got skeleton tokens %v, want %v",
+					requested, db.SkeletonTokens(), skeletonTokens)
+			}
+			db.Close()
+		}
+	}
+}
+
+func TestOpenNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lafzi.db")
+	db, err := Open(ctx, path, LayoutDefault, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, schemaVersion+1))
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db, err = Open(ctx, path, LayoutDefault, false); err == nil {
+		db.Close()
+		t.Errorf("open schema version %d: got no error", schemaVersion+1)
+	}
+}
@@ -1,19 +1,22 @@
 package database
 
-import (
-	"github.com/hablullah/go-lafzi/internal/phonetic"
-)
-
 type Document struct {
-	ID         int    `db:"id"`
-	Identifier string `db:"identifier"`
-	Arabic     string `db:"arabic"`
-	Tokens     []phonetic.NGram
+	ID          int    `db:"id"`
+	Identifier  string `db:"identifier"`
+	Arabic      string `db:"arabic"`
+	Unvocalized bool   `db:"unvocalized"`
+}
+
+type DocumentPhonetic struct {
+	DocumentID  int    `db:"document_id"`
+	Phonetic    []byte `db:"phonetic"`
+	Skeleton    []byte `db:"skeleton"`
+	Unvocalized bool   `db:"unvocalized"`
 }
 
 type DocumentToken struct {
-	DocumentID int    `db:"document_id"`
-	Token      string `db:"token"`
-	Start      int    `db:"start"`
-	End        int    `db:"end"`
+	DocumentID int   `db:"document_id"`
+	Token      int64 `db:"token"`
+	Start      int   `db:"start"`
+	End        int   `db:"end"`
 }
@@ -1,24 +1,77 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/hablullah/go-lafzi/internal/index"
 	"github.com/jmoiron/sqlx"
 )
 
-// Open SQLite database in specified path.
-func Open(path string) (db *sqlx.DB, err error) {
+// Layout is the layout that used to store the document tokens.
+type Layout int
+
+const (
+	// LayoutDefault uses the layout of the existing database,
+	// or LayoutRows for the new database.
+	LayoutDefault Layout = iota
+
+	// LayoutRows stores each token occurence as a row in `document_token`.
+	LayoutRows
+
+	// LayoutPostings stores all occurences of a token as a compressed
+	// posting list in `token_posting`, which split into blocks by
+	// document ID so the new documents only update the last block.
+	LayoutPostings
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutRows:
+		return "rows"
+	case LayoutPostings:
+		return "postings"
+	default:
+		return "default"
+	}
+}
+
+// DB is the SQLite database for storing documents and its tokens.
+type DB struct {
+	*sqlx.DB
+	Layout Layout
+
+	// skeletonTokens is true if the skeleton tokens of vocalized
+	// documents are indexed, as described in index.DocumentTokens.
+	skeletonTokens bool
+}
+
+var _ index.Index = (*DB)(nil)
+
+// Open SQLite database in specified path. If the layout is not default
+// and the database already uses a different layout, error is returned.
+// Likewise, the skeleton tokens are only used by the new database, while
+// the existing database keeps using its own setting. The database
+// that created before the schema is versioned is indexed again, so it uses
+// the requested layout and skeleton tokens like the new database.
+func Open(ctx context.Context, path string, layout Layout, skeletonTokens bool) (_ *DB, err error) {
 	// Prepare DSN
 	q := url.Values{}
 	q.Add("_pragma", "synchronous(0)")
 	q.Add("_pragma", "journal_mode(MEMORY)")
 	q.Add("_pragma", "foreign_keys(1)")
+
+	// The search reads in a single transaction, so the concurrent writes
+	// must wait for it to finish instead of failing immediately
+	q.Add("_pragma", "busy_timeout(10000)")
 	dsn := "file:" + path + "?" + q.Encode()
 
 	// Connect database
-	db, err = sqlx.Connect("sqlite", dsn)
+	db, err := sqlx.ConnectContext(ctx, "sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -26,7 +79,7 @@ func Open(path string) (db *sqlx.DB, err error) {
 
 	// Create transaction
 	var tx *sqlx.Tx
-	tx, err = db.Beginx()
+	tx, err = db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %v", err)
 	}
@@ -37,46 +90,143 @@ func Open(path string) (db *sqlx.DB, err error) {
 			if tx != nil {
 				tx.Rollback()
 			}
-			if db != nil {
-				db.Close()
-			}
-			db = nil
+			db.Close()
 		}
 	}()
 
-	// Generate tables
-	ddlQueries := []string{
-		ddlCreateDocument,
-		ddlCreateDocumentToken,
-		ddlCreateDocumentTokenIndexToken}
+	// Generate tables, or migrate the old ones
+	migrated, err := migrate(ctx, tx, layout, skeletonTokens)
+	if err != nil {
+		return
+	}
 
-	for _, query := range ddlQueries {
-		_, err = tx.Exec(query)
+	// Check the layout and skeleton tokens
+	layout, err = checkLayout(ctx, tx, layout)
+	if err != nil {
+		return
+	}
+
+	skeletonTokens, err = checkSkeletonTokens(ctx, tx, skeletonTokens)
+	if err != nil {
+		return
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return
+	}
+
+	// Migration leaves a lot of unused space, so reclaim it
+	if migrated {
+		_, err = db.ExecContext(ctx, `VACUUM`)
 		if err != nil {
 			return
 		}
 	}
 
-	// Commit transaction
-	err = tx.Commit()
+	return &DB{db, layout, skeletonTokens}, nil
+}
+
+// checkLayout compares the requested layout with the one that saved in
+// database. If the database doesn't have any layout yet, the requested
+// layout will be saved.
+func checkLayout(ctx context.Context, tx *sqlx.Tx, requested Layout) (Layout, error) {
+	// Fetch the saved layout
+	var savedName string
+	err := tx.GetContext(ctx, &savedName, `SELECT value FROM metadata WHERE key = 'layout'`)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	var saved Layout
+	switch savedName {
+	case "", LayoutRows.String():
+		saved = LayoutRows
+	case LayoutPostings.String():
+		saved = LayoutPostings
+	default:
+		return 0, fmt.Errorf("unknown layout %q", savedName)
+	}
+
+	// If layout is not saved yet, the database is just created
+	// so the requested one will be used.
+	if err == sql.ErrNoRows {
+		if requested != LayoutDefault {
+			saved = requested
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO metadata (key, value)
+			VALUES ('layout', ?)`, saved.String())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Make sure the requested layout is the same as the saved one
+	if requested != LayoutDefault && requested != saved {
+		return 0, fmt.Errorf("database already uses %s layout, can't be opened with %s layout",
+			saved, requested)
+	}
+
+	return saved, nil
+}
+
+// checkSkeletonTokens returns the skeleton tokens that saved in database.
+// If it's not saved yet, the database is just created so the requested one
+// will be saved. Otherwise the saved one is used, since the documents must
+// be indexed again to add or remove their skeleton tokens.
+func checkSkeletonTokens(ctx context.Context, tx *sqlx.Tx, requested bool) (bool, error) {
+	saved, found, err := savedSkeletonTokens(ctx, tx)
 	if err != nil {
+		return false, err
+	}
+
+	if !found {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO metadata (key, value)
+			VALUES ('skeleton_tokens', ?)`, strconv.FormatBool(requested))
+		return requested, err
+	}
+
+	return saved, nil
+}
+
+// savedSkeletonTokens returns whether the skeleton tokens are enabled
+// in the database. Returns false if the setting is not saved yet.
+func savedSkeletonTokens(ctx context.Context, tx *sqlx.Tx) (enabled bool, found bool, err error) {
+	var value string
+	err = tx.GetContext(ctx, &value, `SELECT value FROM metadata WHERE key = 'skeleton_tokens'`)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
 		return
 	}
 
-	return
+	enabled, err = strconv.ParseBool(value)
+	return enabled, true, err
+}
+
+// SkeletonTokens reports whether the skeleton tokens of vocalized
+// documents are indexed.
+func (db *DB) SkeletonTokens() bool {
+	return db.skeletonTokens
 }
 
 const ddlCreateDocument = `
 CREATE TABLE IF NOT EXISTS document (
-	id         INTEGER PRIMARY KEY AUTOINCREMENT,
-	identifier TEXT    UNIQUE NOT NULL,
-	arabic     TEXT    NOT NULL,
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	identifier  TEXT    UNIQUE NOT NULL,
+	arabic      TEXT    NOT NULL,
+	unvocalized INTEGER NOT NULL DEFAULT 0,
 	UNIQUE (identifier))`
 
 const ddlCreateDocumentToken = `
 CREATE TABLE IF NOT EXISTS document_token (
 	document_id INTEGER NOT NULL,
-	token       TEXT    NOT NULL,
+	token       INTEGER NOT NULL,
 	start       INTEGER NOT NULL,
 	end         INTEGER NOT NULL,
 	CONSTRAINT token_document_fk
@@ -86,3 +236,31 @@ CREATE TABLE IF NOT EXISTS document_token (
 
 const ddlCreateDocumentTokenIndexToken = `
 CREATE INDEX IF NOT EXISTS document_token_idx_token ON document_token (token)`
+
+const ddlCreateTokenPosting = `
+CREATE TABLE IF NOT EXISTS token_posting (
+	token    INTEGER NOT NULL,
+	first_id INTEGER NOT NULL,
+	last_id  INTEGER NOT NULL,
+	postings BLOB    NOT NULL,
+	PRIMARY KEY (token, first_id))`
+
+const ddlCreateTokenFrequency = `
+CREATE TABLE IF NOT EXISTS token_frequency (
+	token     INTEGER PRIMARY KEY,
+	frequency INTEGER NOT NULL)`
+
+const ddlCreateDocumentPhonetic = `
+CREATE TABLE IF NOT EXISTS document_phonetic (
+	document_id INTEGER PRIMARY KEY,
+	phonetic    BLOB    NOT NULL,
+	skeleton    BLOB    NOT NULL,
+	CONSTRAINT phonetic_document_fk
+		FOREIGN KEY (document_id)
+		REFERENCES document (id)
+		ON DELETE CASCADE)`
+
+const ddlCreateMetadata = `
+CREATE TABLE IF NOT EXISTS metadata (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL)`
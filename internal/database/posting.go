@@ -0,0 +1,284 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/jmoiron/sqlx"
+)
+
+// TokenPosting is a block of the posting list of a token. The posting list
+// is split into blocks by document ID, so the postings of new document only
+// updates the last block instead of the whole list.
+type TokenPosting struct {
+	Token    int64  `db:"token"`
+	FirstID  int    `db:"first_id"`
+	LastID   int    `db:"last_id"`
+	Postings []byte `db:"postings"`
+}
+
+// postingBlock is the range of document IDs in a block of posting list,
+// along with the size of its postings.
+type postingBlock struct {
+	FirstID int `db:"first_id"`
+	LastID  int `db:"last_id"`
+	Size    int `db:"size"`
+}
+
+// maxBlockSize is the size of postings in a block, after which the postings
+// of the next documents are saved in a new block.
+const maxBlockSize = 1024
+
+// updatePostings removes the postings of the removed documents, then adds
+// the new postings into the posting lists. Removed is the IDs of removed
+// documents that use each token, so it must contain every token of the
+// removed documents. Only the blocks that contain the removed or added
+// documents are rewritten, while the postings of new documents are appended
+// into the last block until it's full. The token frequencies are updated
+// as well, using the number of documents in the updated blocks.
+func updatePostings(ctx context.Context, tx *sqlx.Tx, removed map[int64][]int, added map[int64][]index.Posting) (err error) {
+	// Fetch the blocks of posting lists that need to be updated
+	tokens := slices.Collect(maps.Keys(removed))
+	for token := range added {
+		if _, exist := removed[token]; !exist {
+			tokens = append(tokens, token)
+		}
+	}
+
+	tokenBlocks := make(map[int64][]postingBlock)
+	for batch := range slices.Chunk(tokens, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT token, first_id, last_id, LENGTH(postings) size
+			FROM token_posting WHERE token IN (?)
+			ORDER BY token, first_id`, batch)
+		if err != nil {
+			return
+		}
+
+		var rows []struct {
+			Token int64 `db:"token"`
+			postingBlock
+		}
+
+		err = tx.SelectContext(ctx, &rows, tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, row := range rows {
+			tokenBlocks[row.Token] = append(tokenBlocks[row.Token], row.postingBlock)
+		}
+	}
+
+	// Prepare statements
+	stmtGetPostings, err := tx.PreparexContext(ctx, `
+		SELECT postings FROM token_posting
+		WHERE token = ? AND first_id = ?`)
+	if err != nil {
+		return
+	}
+
+	stmtDeletePostings, err := tx.PreparexContext(ctx, `
+		DELETE FROM token_posting
+		WHERE token = ? AND first_id = ?`)
+	if err != nil {
+		return
+	}
+
+	stmtSavePostings, err := tx.PreparexContext(ctx, `
+		INSERT INTO token_posting (token, first_id, last_id, postings)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (token, first_id) DO UPDATE
+		SET last_id = excluded.last_id,
+			postings = excluded.postings`)
+	if err != nil {
+		return
+	}
+
+	// Update the blocks of each token
+	frequencyDeltas := make(map[int64]int)
+	for _, token := range tokens {
+		blocks := tokenBlocks[token]
+		removedIDs := make(map[int]struct{}, len(removed[token]))
+		for _, id := range removed[token] {
+			removedIDs[id] = struct{}{}
+		}
+
+		// Find the blocks that contain the removed and added documents.
+		// The added postings beyond the last block are appended into it if
+		// it's not full yet, otherwise they are saved in the new blocks.
+		changes := make(map[int][]index.Posting)
+		for id := range removedIDs {
+			if i := findPostingBlock(blocks, id); i >= 0 {
+				changes[i] = nil
+			}
+		}
+
+		var newPostings []index.Posting
+		for _, p := range added[token] {
+			i := findPostingBlock(blocks, p.DocumentID)
+			if i < 0 || (i == len(blocks)-1 && p.DocumentID > blocks[i].LastID && blocks[i].Size >= maxBlockSize) {
+				newPostings = append(newPostings, p)
+			} else {
+				changes[i] = append(changes[i], p)
+			}
+		}
+
+		// Rewrite the changed blocks
+		for i, blockPostings := range changes {
+			block := blocks[i]
+			var buf []byte
+			err = stmtGetPostings.GetContext(ctx, &buf, token, block.FirstID)
+			if err != nil {
+				return
+			}
+
+			var postings []index.Posting
+			postings, err = index.DecodePostings(buf)
+			if err != nil {
+				return
+			}
+			nOldDocument := countPostingDocuments(postings)
+
+			postings = slices.DeleteFunc(postings, func(p index.Posting) bool {
+				_, exist := removedIDs[p.DocumentID]
+				return exist
+			})
+			postings = index.SortPostings(append(postings, blockPostings...))
+			frequencyDeltas[token] += countPostingDocuments(postings) - nOldDocument
+
+			// The block is replaced in place, unless its first document
+			// is changed so it must be saved with the new ID
+			if len(postings) == 0 || postings[0].DocumentID != block.FirstID {
+				_, err = stmtDeletePostings.ExecContext(ctx, token, block.FirstID)
+				if err != nil {
+					return
+				}
+			}
+
+			err = savePostingBlocks(ctx, stmtSavePostings, token, postings)
+			if err != nil {
+				return
+			}
+		}
+
+		// Save the postings of the new blocks
+		if len(newPostings) > 0 {
+			newPostings = index.SortPostings(newPostings)
+			frequencyDeltas[token] += countPostingDocuments(newPostings)
+			err = savePostingBlocks(ctx, stmtSavePostings, token, newPostings)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return updateTokenFrequencies(ctx, tx, frequencyDeltas)
+}
+
+// findPostingBlock returns the index of block that the document belongs to,
+// i.e. the last block that starts before the document. If the document is
+// before the first block, it's put in the first one. Returns -1 if there
+// are no blocks.
+func findPostingBlock(blocks []postingBlock, documentID int) int {
+	if len(blocks) == 0 {
+		return -1
+	}
+
+	i, _ := slices.BinarySearchFunc(blocks, documentID, func(b postingBlock, id int) int {
+		return cmp.Compare(b.FirstID, id)
+	})
+
+	if i < len(blocks) && blocks[i].FirstID == documentID {
+		return i
+	}
+	return max(i-1, 0)
+}
+
+// savePostingBlocks splits the sorted postings into blocks, then save them.
+// A new block is started once the current one reaches maxBlockSize, but the
+// postings of a document are always kept in the same block.
+func savePostingBlocks(ctx context.Context, stmt *sqlx.Stmt, token int64, postings []index.Posting) error {
+	var buf []byte
+	var first, prev index.Posting
+	for i, p := range postings {
+		if i > 0 && p.DocumentID != prev.DocumentID && len(buf) >= maxBlockSize {
+			_, err := stmt.ExecContext(ctx, token, first.DocumentID, prev.DocumentID, buf)
+			if err != nil {
+				return err
+			}
+			buf, prev = nil, index.Posting{}
+		}
+
+		if len(buf) == 0 {
+			first = p
+		}
+
+		buf = index.AppendPosting(buf, prev, p)
+		prev = p
+	}
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := stmt.ExecContext(ctx, token, first.DocumentID, prev.DocumentID, buf)
+	return err
+}
+
+// documentTokenCodes returns the IDs of the documents that use each token,
+// for the documents with the specified IDs. The tokens are created from the
+// saved phonetic and flag of the documents, so it must be called before the
+// documents are replaced or removed.
+func documentTokenCodes(ctx context.Context, tx *sqlx.Tx, ids []int, skeletonTokens bool) (codes map[int64][]int, err error) {
+	codes = make(map[int64][]int)
+	for batch := range slices.Chunk(ids, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT p.document_id, p.phonetic, p.skeleton, d.unvocalized
+			FROM document_phonetic p
+			JOIN document d ON d.id = p.document_id
+			WHERE p.document_id IN (?)`, batch)
+		if err != nil {
+			return
+		}
+
+		var rows []DocumentPhonetic
+		err = tx.SelectContext(ctx, &rows, tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, row := range rows {
+			var dp index.DocumentPhonetic
+			if dp, err = decodeDocumentPhonetic(row); err != nil {
+				return nil, err
+			}
+
+			for _, token := range distinctTokenCodes(dp.Tokens(skeletonTokens)) {
+				codes[token] = append(codes[token], row.DocumentID)
+			}
+		}
+	}
+
+	return codes, nil
+}
+
+// countPostingDocuments returns the number of distinct documents in
+// the postings, which must be already sorted by document ID.
+func countPostingDocuments(postings []index.Posting) int {
+	var n int
+	for i, p := range postings {
+		if i == 0 || p.DocumentID != postings[i-1].DocumentID {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+func TestPostingBlocks(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, filepath.Join(t.TempDir(), "lafzi.db"), LayoutPostings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	texts := []string{
+		"قُلْ هُوَ اللَّهُ أَحَدٌ",
+		"اللَّهُ الصَّمَدُ",
+		"قُلْ أَعُوذُ بِرَبِّ النَّاسِ",
+	}
+
+	newArg := func(i, text int) index.InsertDocumentArg {
+		return index.NewInsertDocumentArg(fmt.Sprintf("doc-%d", i), texts[text], false)
+	}
+
+	// The common tokens are split into several blocks by the first insert,
+	// then the next documents are appended into the last block one by one.
+	want := make(map[string]index.InsertDocumentArg)
+	var args []index.InsertDocumentArg
+	for i := range 2000 {
+		args = append(args, newArg(i, i%2))
+	}
+
+	steps := []struct {
+		name   string
+		insert [][]index.InsertDocumentArg
+		delete []string
+	}{
+		{name: "insert", insert: [][]index.InsertDocumentArg{args}},
+		{name: "append", insert: [][]index.InsertDocumentArg{
+			{newArg(2000, 0)}, {newArg(2001, 1)}, {newArg(2002, 0)}}},
+		{name: "replace middle", insert: [][]index.InsertDocumentArg{
+			{newArg(1000, 2), newArg(1500, 2)}}},
+		{name: "replace before first block", insert: [][]index.InsertDocumentArg{
+			{newArg(5, 2)}}},
+		{name: "delete", delete: []string{"doc-0", "doc-5", "doc-999", "doc-1000", "doc-2002"}},
+	}
+
+	for _, step := range steps {
+		for _, batch := range step.insert {
+			if err := db.InsertDocuments(ctx, batch...); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, arg := range batch {
+				want[arg.Identifier] = arg
+			}
+		}
+
+		if err := db.DeleteDocuments(ctx, step.delete...); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, identifier := range step.delete {
+			delete(want, identifier)
+		}
+
+		checkPostingBlocks(t, step.name, db, slices.Collect(maps.Values(want)))
+	}
+}
+
+// checkPostingBlocks makes sure the blocks of each posting list are sorted
+// without overlapping, and the postings are the same as the documents.
+func checkPostingBlocks(t *testing.T, step string, db *DB, want []index.InsertDocumentArg) {
+	t.Helper()
+	ctx := context.Background()
+
+	var blocks []TokenPosting
+	err := db.SelectContext(ctx, &blocks, `
+		SELECT token, first_id, last_id, postings
+		FROM token_posting ORDER BY token, first_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotPostings := make(map[int64][]index.Posting)
+	nBlocks := make(map[int64]int)
+	for i, block := range blocks {
+		postings, err := index.DecodePostings(block.Postings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first, last := postings[0].DocumentID, postings[len(postings)-1].DocumentID; first != block.FirstID || last != block.LastID {
+			t.Errorf("%s: block of %d has range %d-%d, want %d-%d",
+				step, block.Token, block.FirstID, block.LastID, first, last)
+		}
+
+		if i > 0 && blocks[i-1].Token == block.Token && blocks[i-1].LastID >= block.FirstID {
+			t.Errorf("%s: block of %d at %d overlaps the previous one", step, block.Token, block.FirstID)
+		}
+
+		gotPostings[block.Token] = append(gotPostings[block.Token], postings...)
+		nBlocks[block.Token]++
+	}
+
+	if slices.Max(slices.Collect(maps.Values(nBlocks))) < 2 {
+		t.Errorf("%s: every token only has one block", step)
+	}
+
+	// Compare the postings with the tokens of wanted documents
+	wantPostings := make(map[int64][]index.Posting)
+	for _, arg := range want {
+		doc, found, err := findDocument(ctx, db, arg.Identifier)
+		if err != nil || !found {
+			t.Fatalf("%s: find %s: %v %v", step, arg.Identifier, found, err)
+		}
+
+		for _, token := range index.DocumentTokens(arg, db.SkeletonTokens()) {
+			wantPostings[token.Code] = append(wantPostings[token.Code], index.Posting{
+				DocumentID: doc.ID,
+				Start:      token.Start,
+				End:        token.End,
+			})
+		}
+	}
+
+	for token, postings := range wantPostings {
+		wantPostings[token] = index.SortPostings(postings)
+	}
+
+	if !maps.EqualFunc(gotPostings, wantPostings, slices.Equal) {
+		t.Errorf("%s: got postings of %d tokens, want %d", step, len(gotPostings), len(wantPostings))
+	}
+
+	// The frequencies must be updated using the changed blocks
+	var frequencies []TokenFrequency
+	err = db.SelectContext(ctx, &frequencies, `SELECT token, frequency FROM token_frequency`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotFrequencies := make(map[int64]int)
+	for _, tf := range frequencies {
+		gotFrequencies[tf.Token] = tf.Frequency
+	}
+
+	wantFrequencies := make(map[int64]int)
+	for token, postings := range wantPostings {
+		wantFrequencies[token] = countPostingDocuments(postings)
+	}
+
+	if !maps.Equal(gotFrequencies, wantFrequencies) {
+		t.Errorf("%s: got frequencies of %d tokens, want %d", step, len(gotFrequencies), len(wantFrequencies))
+	}
+}
+
+func findDocument(ctx context.Context, db *DB, identifier string) (doc index.Document, found bool, err error) {
+	err = db.View(ctx, func(r index.Reader) (err error) {
+		doc, found, err = r.FindDocument(ctx, identifier)
+		return
+	})
+	return
+}
@@ -0,0 +1,387 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxBatchSize is the max number of parameters that used in a single
+// `IN (...)` query. SQLite limits the number of parameters in a query,
+// so the values must be split into several batches.
+const maxBatchSize = 500
+
+// reader reads the database within the read transaction that created by
+// View, so all of its reads see the same state of the database.
+type reader struct {
+	tx     *sqlx.Tx
+	layout Layout
+}
+
+var _ index.Reader = reader{}
+
+// View calls fn with the reader that uses a single read transaction, which
+// is rolled back once fn returns.
+func (db *DB) View(ctx context.Context, fn func(r index.Reader) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	return fn(reader{tx, db.Layout})
+}
+
+// LookupTokens fetch the locations of the specified tokens in all documents.
+func (r reader) LookupTokens(ctx context.Context, tokens []int64) (locations []index.TokenLocation, err error) {
+	// If there are no tokens submitted, stop early
+	if len(tokens) == 0 {
+		return
+	}
+
+	// Look up the tokens per batch
+	for batch := range slices.Chunk(tokens, maxBatchSize) {
+		var batchLocations []index.TokenLocation
+		switch r.layout {
+		case LayoutPostings:
+			batchLocations, err = lookupPostings(ctx, r.tx, batch)
+		default:
+			batchLocations, err = lookupRows(ctx, r.tx, batch)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, batchLocations...)
+	}
+
+	return
+}
+
+func lookupRows(ctx context.Context, tx *sqlx.Tx, tokens []int64) ([]index.TokenLocation, error) {
+	query, args, err := sqlx.In(`
+		SELECT document_id, token, start, end
+		FROM document_token
+		WHERE token IN (?)`, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	var docTokens []DocumentToken
+	err = tx.SelectContext(ctx, &docTokens, tx.Rebind(query), args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	locations := make([]index.TokenLocation, len(docTokens))
+	for i, dt := range docTokens {
+		locations[i] = index.TokenLocation{
+			DocumentID: dt.DocumentID,
+			Token:      dt.Token,
+			Start:      dt.Start,
+			End:        dt.End,
+		}
+	}
+
+	return locations, nil
+}
+
+func lookupPostings(ctx context.Context, tx *sqlx.Tx, tokens []int64) ([]index.TokenLocation, error) {
+	query, args, err := sqlx.In(`
+		SELECT token, postings
+		FROM token_posting
+		WHERE token IN (?)`, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenPostings []TokenPosting
+	err = tx.SelectContext(ctx, &tokenPostings, tx.Rebind(query), args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var locations []index.TokenLocation
+	for _, tp := range tokenPostings {
+		postings, err := index.DecodePostings(tp.Postings)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range postings {
+			locations = append(locations, index.TokenLocation{
+				DocumentID: p.DocumentID,
+				Token:      tp.Token,
+				Start:      p.Start,
+				End:        p.End,
+			})
+		}
+	}
+
+	return locations, nil
+}
+
+// LookupDocumentTokens fetch the locations of the specified tokens, but only
+// in the document with the specified ID.
+func (r reader) LookupDocumentTokens(ctx context.Context, documentID int, tokens []int64) (locations []index.TokenLocation, err error) {
+	// If there are no tokens submitted, stop early
+	if len(tokens) == 0 {
+		return
+	}
+
+	// Look up the tokens per batch
+	for batch := range slices.Chunk(tokens, maxBatchSize) {
+		var batchLocations []index.TokenLocation
+		switch r.layout {
+		case LayoutPostings:
+			batchLocations, err = lookupDocumentPostings(ctx, r.tx, documentID, batch)
+		default:
+			batchLocations, err = lookupDocumentRows(ctx, r.tx, documentID, batch)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, batchLocations...)
+	}
+
+	return
+}
+
+func lookupDocumentRows(ctx context.Context, tx *sqlx.Tx, documentID int, tokens []int64) ([]index.TokenLocation, error) {
+	query, args, err := sqlx.In(`
+		SELECT document_id, token, start, end
+		FROM document_token
+		WHERE token IN (?) AND document_id = ?`, tokens, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var docTokens []DocumentToken
+	err = tx.SelectContext(ctx, &docTokens, tx.Rebind(query), args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	locations := make([]index.TokenLocation, len(docTokens))
+	for i, dt := range docTokens {
+		locations[i] = index.TokenLocation{
+			DocumentID: dt.DocumentID,
+			Token:      dt.Token,
+			Start:      dt.Start,
+			End:        dt.End,
+		}
+	}
+
+	return locations, nil
+}
+
+func lookupDocumentPostings(ctx context.Context, tx *sqlx.Tx, documentID int, tokens []int64) ([]index.TokenLocation, error) {
+	query, args, err := sqlx.In(`
+		SELECT token, postings
+		FROM token_posting
+		WHERE token IN (?) AND first_id <= ? AND last_id >= ?`,
+		tokens, documentID, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenPostings []TokenPosting
+	err = tx.SelectContext(ctx, &tokenPostings, tx.Rebind(query), args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Only the block of the document is fetched, and it's only
+	// decoded until the document is passed
+	var locations []index.TokenLocation
+	for _, tp := range tokenPostings {
+		postings, err := index.DecodeDocumentPostings(tp.Postings, documentID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range postings {
+			locations = append(locations, index.TokenLocation{
+				DocumentID: p.DocumentID,
+				Token:      tp.Token,
+				Start:      p.Start,
+				End:        p.End,
+			})
+		}
+	}
+
+	return locations, nil
+}
+
+// FetchDocuments fetch the documents with the specified IDs. The documents
+// are fetched in batches, to reduce the round trip to database.
+func (r reader) FetchDocuments(ctx context.Context, ids []int) (docs []index.Document, err error) {
+	for batch := range slices.Chunk(ids, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT id, identifier, arabic
+			FROM document WHERE id IN (?)`, batch)
+		if err != nil {
+			return
+		}
+
+		var batchDocs []Document
+		err = r.tx.SelectContext(ctx, &batchDocs, r.tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, doc := range batchDocs {
+			docs = append(docs, index.Document{
+				ID:         doc.ID,
+				Identifier: doc.Identifier,
+				Arabic:     doc.Arabic,
+			})
+		}
+	}
+
+	return docs, nil
+}
+
+// DocumentLengths fetch the number of runes in the text of documents with the
+// specified IDs. Like FetchDocuments, it's fetched in batches.
+func (r reader) DocumentLengths(ctx context.Context, ids []int) (lengths map[int]int, err error) {
+	lengths = make(map[int]int, len(ids))
+	for batch := range slices.Chunk(ids, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT id, LENGTH(arabic) length
+			FROM document WHERE id IN (?)`, batch)
+		if err != nil {
+			return
+		}
+
+		var rows []struct {
+			ID     int `db:"id"`
+			Length int `db:"length"`
+		}
+
+		err = r.tx.SelectContext(ctx, &rows, r.tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, row := range rows {
+			lengths[row.ID] = row.Length
+		}
+	}
+
+	return lengths, nil
+}
+
+// DocumentPhonetics fetch the phonetic of documents with the specified IDs.
+// Like FetchDocuments, it's fetched in batches.
+func (r reader) DocumentPhonetics(ctx context.Context, ids []int) (phonetics map[int]index.DocumentPhonetic, err error) {
+	phonetics = make(map[int]index.DocumentPhonetic, len(ids))
+	for batch := range slices.Chunk(ids, maxBatchSize) {
+		var query string
+		var args []any
+		query, args, err = sqlx.In(`
+			SELECT p.document_id, p.phonetic, p.skeleton, d.unvocalized
+			FROM document_phonetic p
+			JOIN document d ON d.id = p.document_id
+			WHERE p.document_id IN (?)`, batch)
+		if err != nil {
+			return
+		}
+
+		var rows []DocumentPhonetic
+		err = r.tx.SelectContext(ctx, &rows, r.tx.Rebind(query), args...)
+		if err != nil && err != sql.ErrNoRows {
+			return
+		}
+
+		for _, row := range rows {
+			var dp index.DocumentPhonetic
+			if dp, err = decodeDocumentPhonetic(row); err != nil {
+				return nil, err
+			}
+
+			phonetics[row.DocumentID] = dp
+		}
+	}
+
+	return phonetics, nil
+}
+
+// ScanDocumentPhonetics calls fn with the phonetic of every document, sorted
+// by their IDs. The phonetics are fetched in batches, so the documents after
+// the scan is stopped are never fetched.
+func (r reader) ScanDocumentPhonetics(ctx context.Context, fn func(id int, dp index.DocumentPhonetic) bool) error {
+	var lastID int
+	for {
+		var rows []DocumentPhonetic
+		err := r.tx.SelectContext(ctx, &rows, `
+			SELECT p.document_id, p.phonetic, p.skeleton, d.unvocalized
+			FROM document_phonetic p
+			JOIN document d ON d.id = p.document_id
+			WHERE p.document_id > ?
+			ORDER BY p.document_id LIMIT ?`, lastID, maxBatchSize)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		for _, row := range rows {
+			dp, err := decodeDocumentPhonetic(row)
+			if err != nil {
+				return err
+			}
+
+			if !fn(row.DocumentID, dp) {
+				return nil
+			}
+		}
+
+		if len(rows) < maxBatchSize {
+			return nil
+		}
+
+		lastID = rows[len(rows)-1].DocumentID
+	}
+}
+
+// decodeDocumentPhonetic decodes the phonetic and skeleton in the row.
+func decodeDocumentPhonetic(row DocumentPhonetic) (dp index.DocumentPhonetic, err error) {
+	dp.Unvocalized = row.Unvocalized
+	if dp.Phonetic, err = index.DecodePhonetic(row.Phonetic); err != nil {
+		return
+	}
+
+	dp.Skeleton, err = index.DecodePhonetic(row.Skeleton)
+	return
+}
+
+// FindDocument fetch the document with the specified identifier.
+func (r reader) FindDocument(ctx context.Context, identifier string) (doc index.Document, found bool, err error) {
+	var dbDoc Document
+	err = r.tx.GetContext(ctx, &dbDoc, `
+		SELECT id, identifier, arabic
+		FROM document WHERE identifier = ?`, identifier)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+		return
+	}
+
+	doc = index.Document{
+		ID:         dbDoc.ID,
+		Identifier: dbDoc.Identifier,
+		Arabic:     dbDoc.Arabic,
+	}
+	return doc, true, nil
+}
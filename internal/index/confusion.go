@@ -0,0 +1,78 @@
+package index
+
+// ConfusionMatrix is the cost of substituting a phonetic rune with another
+// rune that often confused with it, between 0 (the same) and 1 (completely
+// different). The matrix is symmetric, so each pair only needs to be saved
+// once. The pairs that not in the matrix are completely different.
+type ConfusionMatrix map[[2]rune]float64
+
+// Cost returns the cost of substituting rune a with rune b.
+func (cm ConfusionMatrix) Cost(a, b rune) float64 {
+	if a == b {
+		return 0
+	}
+
+	if cost, exist := cm[[2]rune{a, b}]; exist {
+		return min(max(cost, 0), 1)
+	}
+
+	if cost, exist := cm[[2]rune{b, a}]; exist {
+		return min(max(cost, 0), 1)
+	}
+
+	return 1
+}
+
+// confusions returns the runes that might be confused with rune r, along
+// with the cost of each substitution. The completely different runes are
+// not returned.
+func (cm ConfusionMatrix) confusions(r rune) map[rune]float64 {
+	result := make(map[rune]float64)
+	for pair := range cm {
+		var other rune
+		switch r {
+		case pair[0]:
+			other = pair[1]
+		case pair[1]:
+			other = pair[0]
+		default:
+			continue
+		}
+
+		if cost := cm.Cost(r, other); cost < 1 {
+			result[other] = cost
+		}
+	}
+	return result
+}
+
+// tokenVariants returns the tokens that created by substituting a single
+// rune in the token with the rune that might be confused with it, along
+// with the cost of the substitution.
+func (cm ConfusionMatrix) tokenVariants(token int64) map[int64]float64 {
+	if len(cm) == 0 {
+		return nil
+	}
+
+	kind, ngram := DecodeToken(token)
+	runes := []rune(ngram)
+	variants := make(map[int64]float64)
+	for i, r := range runes {
+		for other, cost := range cm.confusions(r) {
+			variant := make([]rune, len(runes))
+			copy(variant, runes)
+			variant[i] = other
+
+			code := EncodeToken(kind, string(variant))
+			if code == 0 || code == token {
+				continue
+			}
+
+			if oldCost, exist := variants[code]; !exist || cost < oldCost {
+				variants[code] = cost
+			}
+		}
+	}
+
+	return variants
+}
@@ -0,0 +1,119 @@
+package index
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// Explanation is the breakdown of how a document is matched and scored by
+// SearchTokens. Locations and Groups are listed per query, in the same order
+// as the queries.
+type Explanation struct {
+	// Locations is the locations of the query tokens in the document, where
+	// the TokenID is the index of the token in its query.
+	Locations [][]TokenLocation
+
+	// Groups is the groups of token locations along with their scores,
+	// including the groups whose confidence is below the minimum.
+	Groups [][]TokenLocationGroup
+
+	// Confidence and Positions are the same as the ones in SearchResult.
+	// If the document is not found by the search, the confidence is zero.
+	// If the search is re-ranked, they are re-scored by the alignment.
+	Confidence float64
+	Positions  [][2]int
+}
+
+// ExplainTokens explains how the document with the specified ID is matched
+// and scored by SearchTokens using the same options and queries. Like
+// SearchTokens, the reader should be created by Index.View.
+func ExplainTokens(ctx context.Context, r Reader, opts SearchOptions, documentID int, queries ...TokenQuery) (exp Explanation, err error) {
+	exp.Locations = make([][]TokenLocation, len(queries))
+	exp.Groups = make([][]TokenLocationGroup, len(queries))
+
+	// If there are no tokens submitted, match the short queries directly
+	// against the document phonetic, the same way as SearchTokens.
+	ordinalQueries, tokenOrdinals := mapTokenOrdinals(queries, opts.Confusions)
+	if len(ordinalQueries) == 0 {
+		if !slices.ContainsFunc(queries, isShortQuery) {
+			return
+		}
+
+		var phonetics map[int]DocumentPhonetic
+		phonetics, err = r.DocumentPhonetics(ctx, []int{documentID})
+		if err != nil {
+			return
+		}
+
+		if dp, exist := phonetics[documentID]; exist {
+			exp.Confidence, exp.Positions = matchShortQueries(queries, dp, opts)
+		}
+		return
+	}
+
+	// Look up the tokens, but only in the document
+	locations, err := r.LookupDocumentTokens(ctx, documentID, distinctTokens(tokenOrdinals))
+	if err != nil {
+		return
+	}
+
+	// Group the token locations without any minimum, so every group is kept
+	groupOpts := opts
+	groupOpts.MinConfidence = 0
+	flatTokenLocations := flattenLocations(locations, ordinalQueries, tokenOrdinals)
+	scoring, err := fetchScoringData(ctx, r, flatTokenLocations, queries, opts)
+	if err != nil {
+		return
+	}
+
+	groups, err := groupLocations(ctx, flatTokenLocations, ordinalQueries, queries, scoring, groupOpts)
+	if err != nil {
+		return
+	}
+
+	// Split the locations and groups by its query. The first ordinal of
+	// each query is used to find the index of token within the query.
+	firstOrdinals := queryFirstOrdinals(ordinalQueries, len(queries))
+
+	for _, tl := range flatTokenLocations {
+		query := ordinalQueries[tl.TokenID]
+		tl.TokenID -= firstOrdinals[query]
+		exp.Locations[query] = append(exp.Locations[query], tl)
+	}
+
+	var matchedGroups []TokenLocationGroup
+	for _, g := range groups {
+		exp.Groups[g.Query] = append(exp.Groups[g.Query], g)
+		if g.Confidence >= opts.MinConfidence {
+			matchedGroups = append(matchedGroups, g)
+		}
+	}
+
+	// Merge the matched groups the same way as SearchTokens
+	slices.SortFunc(matchedGroups, func(a, b TokenLocationGroup) int {
+		if a.Start != b.Start {
+			return cmp.Compare(a.Start, b.Start)
+		}
+		return -cmp.Compare(a.Confidence, b.Confidence)
+	})
+
+	for _, g := range matchedGroups {
+		exp.Confidence = max(exp.Confidence, g.Confidence)
+		exp.Positions = append(exp.Positions, [2]int{g.Start, g.End})
+	}
+
+	// Re-score the matched groups by aligning their queries, as if the
+	// document is one of the top results that re-ranked by SearchTokens.
+	if opts.Rerank > 0 && len(matchedGroups) > 0 {
+		var phonetics map[int]DocumentPhonetic
+		phonetics, err = r.DocumentPhonetics(ctx, []int{documentID})
+		if err != nil {
+			return
+		}
+
+		exp.Confidence, exp.Positions = alignGroups(matchedGroups, phonetics[documentID], queries, opts)
+	}
+
+	return
+}
@@ -0,0 +1,111 @@
+package index
+
+import (
+	"context"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// Index is the storage for documents and the locations of their tokens.
+// Each token is a n-gram that encoded using EncodeToken.
+type Index interface {
+	// InsertDocuments save the documents and their tokens. If a document
+	// with the same identifier already exists, it will be replaced.
+	InsertDocuments(ctx context.Context, args ...InsertDocumentArg) error
+
+	// DeleteDocuments remove the documents and their tokens.
+	DeleteDocuments(ctx context.Context, identifiers ...string) error
+
+	// View calls fn with the reader that sees the index as it is when the
+	// reading starts, so all reads within fn are consistent with each other
+	// even if the documents are changed concurrently. The reader is only
+	// valid until fn returns. Since the index might be locked until then,
+	// fn must not modify the index.
+	View(ctx context.Context, fn func(r Reader) error) error
+
+	// SkeletonTokens reports whether the skeleton tokens of vocalized
+	// documents are indexed, as described in DocumentTokens.
+	SkeletonTokens() bool
+
+	// Close releases the resources that used by the index.
+	Close() error
+}
+
+// Reader reads the documents and their tokens from the index. It's created
+// by Index.View, so every read sees the same state of the index.
+type Reader interface {
+	// LookupTokens returns the locations of the tokens in all documents.
+	LookupTokens(ctx context.Context, tokens []int64) ([]TokenLocation, error)
+
+	// LookupDocumentTokens returns the locations of the tokens in a single
+	// document, which is cheaper than looking them up in all documents.
+	LookupDocumentTokens(ctx context.Context, documentID int, tokens []int64) ([]TokenLocation, error)
+
+	// FetchDocuments returns the documents with the specified IDs. Missing
+	// documents are skipped, and the order of result is not guaranteed.
+	FetchDocuments(ctx context.Context, ids []int) ([]Document, error)
+
+	// DocumentLengths returns the number of runes in the text of documents
+	// with the specified IDs. Missing documents are skipped.
+	DocumentLengths(ctx context.Context, ids []int) (map[int]int, error)
+
+	// TokenFrequencies returns the number of documents that contain each
+	// token, along with the number of all documents. The tokens that not
+	// used by any documents are skipped.
+	TokenFrequencies(ctx context.Context, tokens []int64) (map[int64]int, int, error)
+
+	// DocumentPhonetics returns the phonetic that saved for documents with
+	// the specified IDs. Missing documents are skipped.
+	DocumentPhonetics(ctx context.Context, ids []int) (map[int]DocumentPhonetic, error)
+
+	// ScanDocumentPhonetics calls fn with the phonetic of every document,
+	// sorted by their IDs. The scan stops once fn returns false.
+	ScanDocumentPhonetics(ctx context.Context, fn func(id int, dp DocumentPhonetic) bool) error
+
+	// FindDocument returns the document with the specified identifier.
+	// Returns false if the document doesn't exist.
+	FindDocument(ctx context.Context, identifier string) (Document, bool, error)
+}
+
+type Document struct {
+	ID         int
+	Identifier string
+	Arabic     string
+}
+
+type InsertDocumentArg struct {
+	Identifier  string
+	Arabic      string
+	Phonetic    phonetic.Group
+	Skeleton    phonetic.Group
+	Unvocalized bool
+}
+
+// NewInsertDocumentArg converts the Arabic text into the phonetic and
+// skeleton that needed to index the document.
+func NewInsertDocumentArg(identifier, arabic string, unvocalized bool) InsertDocumentArg {
+	arg := InsertDocumentArg{
+		Identifier:  identifier,
+		Arabic:      arabic,
+		Skeleton:    phonetic.SkeletonFromArabic(arabic),
+		Unvocalized: unvocalized,
+	}
+
+	if !unvocalized {
+		arg.Phonetic = phonetic.FromArabic(arabic)
+	}
+
+	return arg
+}
+
+type TokenLocation struct {
+	DocumentID int
+	TokenID    int
+	Token      int64
+	Start      int
+	End        int
+
+	// Cost is the substitution cost if the token is matched using
+	// the confused runes, or zero if it's matched exactly.
+	Cost float64
+}
@@ -0,0 +1,343 @@
+package index_test
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hablullah/go-lafzi/internal/database"
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/hablullah/go-lafzi/internal/memory"
+	_ "modernc.org/sqlite"
+)
+
+// testIndex is an implementation of index.Index that tested by the suite.
+// Beside the empty index, open returns the function that reopens it, e.g.
+// by loading its snapshot or opening the database file again, so the test
+// can check that nothing is lost once the index is persisted.
+type testIndex struct {
+	name string
+	open func(t *testing.T) (index.Index, func(index.Index) (index.Index, error))
+}
+
+var testIndexes = []testIndex{
+	{"memory", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestMemory(t, false)
+	}},
+	{"memory-skeleton", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestMemory(t, true)
+	}},
+	{"sqlite-rows", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestDatabase(t, database.LayoutRows, false)
+	}},
+	{"sqlite-rows-skeleton", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestDatabase(t, database.LayoutRows, true)
+	}},
+	{"sqlite-postings", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestDatabase(t, database.LayoutPostings, false)
+	}},
+	{"sqlite-postings-skeleton", func(t *testing.T) (index.Index, func(index.Index) (index.Index, error)) {
+		return openTestDatabase(t, database.LayoutPostings, true)
+	}},
+}
+
+func openTestMemory(t *testing.T, skeletonTokens bool) (index.Index, func(index.Index) (index.Index, error)) {
+	reopen := func(idx index.Index) (index.Index, error) {
+		var buf bytes.Buffer
+		if err := idx.(*memory.Index).WriteSnapshot(&buf); err != nil {
+			return nil, err
+		}
+
+		idx.Close()
+		return memory.LoadSnapshot(&buf)
+	}
+
+	return memory.New(skeletonTokens), reopen
+}
+
+func openTestDatabase(t *testing.T, layout database.Layout, skeletonTokens bool) (index.Index, func(index.Index) (index.Index, error)) {
+	path := filepath.Join(t.TempDir(), "lafzi.db")
+	reopen := func(idx index.Index) (index.Index, error) {
+		idx.Close()
+		return database.Open(context.Background(), path, database.LayoutDefault, false)
+	}
+
+	db, err := database.Open(context.Background(), path, layout, skeletonTokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, reopen
+}
+
+func TestIndex(t *testing.T) {
+	var (
+		ikhlas    = index.NewInsertDocumentArg("ikhlas", "قُلْ هُوَ اللَّهُ أَحَدٌ", false)
+		nas       = index.NewInsertDocumentArg("ikhlas", "قُلْ أَعُوذُ بِرَبِّ النَّاسِ", false)
+		samad     = index.NewInsertDocumentArg("samad", "اللَّهُ الصَّمَدُ", false)
+		basmalah  = index.NewInsertDocumentArg("basmalah", "بسم الله الرحمن الرحيم", true)
+		basmalah2 = index.NewInsertDocumentArg("basmalah", "بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ", false)
+		la        = index.NewInsertDocumentArg("la", "لا", true)
+	)
+
+	// Look up the tokens of every text, including the replaced ones
+	// which must not be found anymore, and the skeleton tokens that
+	// must only be found if they are enabled.
+	var tokens []int64
+	for _, arg := range []index.InsertDocumentArg{ikhlas, nas, samad, basmalah, basmalah2, la} {
+		for _, token := range index.DocumentTokens(arg, true) {
+			tokens = append(tokens, token.Code)
+		}
+
+		for _, token := range index.Tokens(index.SkeletonToken, arg.Skeleton) {
+			tokens = append(tokens, token.Code)
+		}
+	}
+
+	slices.Sort(tokens)
+	tokens = slices.Compact(tokens)
+
+	steps := []struct {
+		name   string
+		insert []index.InsertDocumentArg
+		delete []string
+		want   []index.InsertDocumentArg
+	}{{
+		name:   "insert",
+		insert: []index.InsertDocumentArg{ikhlas, samad, basmalah, la},
+		want:   []index.InsertDocumentArg{ikhlas, samad, basmalah, la},
+	}, {
+		name:   "replace",
+		insert: []index.InsertDocumentArg{nas, basmalah2},
+		want:   []index.InsertDocumentArg{nas, samad, basmalah2, la},
+	}, {
+		name:   "delete",
+		delete: []string{"samad", "missing"},
+		want:   []index.InsertDocumentArg{nas, basmalah2, la},
+	}, {
+		name:   "insert deleted",
+		insert: []index.InsertDocumentArg{samad},
+		want:   []index.InsertDocumentArg{nas, basmalah2, la, samad},
+	}, {
+		name:   "replace twice",
+		insert: []index.InsertDocumentArg{ikhlas, nas, ikhlas},
+		want:   []index.InsertDocumentArg{ikhlas, basmalah2, la, samad},
+	}, {
+		name:   "delete all",
+		delete: []string{"ikhlas", "samad", "basmalah", "la"},
+		want:   nil,
+	}}
+
+	for _, ti := range testIndexes {
+		t.Run(ti.name, func(t *testing.T) {
+			ctx := context.Background()
+			idx, reopen := ti.open(t)
+			defer func() { idx.Close() }()
+
+			var seenIDs []int
+			for _, step := range steps {
+				if err := idx.InsertDocuments(ctx, step.insert...); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := idx.DeleteDocuments(ctx, step.delete...); err != nil {
+					t.Fatal(err)
+				}
+
+				seenIDs = checkIndex(t, step.name, idx, step.want, tokens, seenIDs)
+
+				var err error
+				if idx, err = reopen(idx); err != nil {
+					t.Fatal(err)
+				}
+
+				seenIDs = checkIndex(t, step.name+" then reopen", idx, step.want, tokens, seenIDs)
+			}
+		})
+	}
+}
+
+// checkIndex makes sure the index only contains the wanted documents and
+// their tokens. The seen IDs are the IDs of previous documents, which must
+// be skipped if they are not used anymore. Returns the seen IDs that
+// updated with the IDs of the wanted documents.
+func checkIndex(t *testing.T, step string, idx index.Index, want []index.InsertDocumentArg, tokens []int64, seenIDs []int) []int {
+	t.Helper()
+	err := idx.View(context.Background(), func(r index.Reader) error {
+		seenIDs = checkReader(t, step, r, idx.SkeletonTokens(), want, tokens, seenIDs)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seenIDs
+}
+
+// checkReader is the same as checkIndex, but using the reader of the index.
+func checkReader(t *testing.T, step string, r index.Reader, skeletonTokens bool, want []index.InsertDocumentArg, tokens []int64, seenIDs []int) []int {
+	t.Helper()
+	ctx := context.Background()
+
+	// Find the documents by their identifiers
+	wantDocs := make(map[int]index.Document)
+	wantArgs := make(map[int]index.InsertDocumentArg)
+	for _, arg := range want {
+		doc, found, err := r.FindDocument(ctx, arg.Identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !found || doc.Identifier != arg.Identifier || doc.Arabic != arg.Arabic {
+			t.Errorf("%s: find %s: got %+v %v, want text %q", step, arg.Identifier, doc, found, arg.Arabic)
+			continue
+		}
+
+		wantDocs[doc.ID] = doc
+		wantArgs[doc.ID] = arg
+		seenIDs = append(seenIDs, doc.ID)
+	}
+
+	if _, found, _ := r.FindDocument(ctx, "missing"); found {
+		t.Errorf("%s: find missing document: got found", step)
+	}
+
+	slices.Sort(seenIDs)
+	seenIDs = slices.Compact(seenIDs)
+
+	// Fetch the documents, where the unused IDs must be skipped
+	docs, err := r.FetchDocuments(ctx, seenIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotDocs := make(map[int]index.Document)
+	for _, doc := range docs {
+		gotDocs[doc.ID] = doc
+	}
+
+	if !maps.Equal(gotDocs, wantDocs) {
+		t.Errorf("%s: fetch documents: got %v, want %v", step, gotDocs, wantDocs)
+	}
+
+	// Look up the tokens in all documents
+	var wantLocations []index.TokenLocation
+	wantFrequencies := make(map[int64]int)
+	for id, arg := range wantArgs {
+		codes := make(map[int64]bool)
+		for _, token := range index.DocumentTokens(arg, skeletonTokens) {
+			codes[token.Code] = true
+			wantLocations = append(wantLocations, index.TokenLocation{
+				DocumentID: id,
+				Token:      token.Code,
+				Start:      token.Start,
+				End:        token.End,
+			})
+		}
+
+		for code := range codes {
+			wantFrequencies[code]++
+		}
+	}
+
+	locations, err := r.LookupTokens(ctx, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sortLocations(locations)
+	sortLocations(wantLocations)
+	if !slices.Equal(locations, wantLocations) {
+		t.Errorf("%s: look up tokens: got %d locations, want %d", step, len(locations), len(wantLocations))
+	}
+
+	// Look up the tokens in each document
+	for _, id := range seenIDs {
+		locations, err := r.LookupDocumentTokens(ctx, id, tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantDocLocations := slices.DeleteFunc(slices.Clone(wantLocations), func(tl index.TokenLocation) bool {
+			return tl.DocumentID != id
+		})
+
+		sortLocations(locations)
+		if !slices.Equal(locations, wantDocLocations) {
+			t.Errorf("%s: look up tokens in document %d: got %d locations, want %d",
+				step, id, len(locations), len(wantDocLocations))
+		}
+	}
+
+	// Count the token frequencies
+	frequencies, nDocuments, err := r.TokenFrequencies(ctx, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nDocuments != len(want) || !maps.Equal(frequencies, wantFrequencies) {
+		t.Errorf("%s: token frequencies: got %d documents and %d tokens, want %d and %d",
+			step, nDocuments, len(frequencies), len(want), len(wantFrequencies))
+	}
+
+	// Fetch the lengths and phonetics
+	lengths, err := r.DocumentLengths(ctx, seenIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	phonetics, err := r.DocumentPhonetics(ctx, seenIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lengths) != len(want) || len(phonetics) != len(want) {
+		t.Errorf("%s: got %d lengths and %d phonetics, want %d",
+			step, len(lengths), len(phonetics), len(want))
+	}
+
+	for id, arg := range wantArgs {
+		if length := utf8.RuneCountInString(arg.Arabic); lengths[id] != length {
+			t.Errorf("%s: length of %s: got %d, want %d", step, arg.Identifier, lengths[id], length)
+		}
+
+		dp := phonetics[id]
+		if !slices.Equal(dp.Phonetic, arg.Phonetic) || !slices.Equal(dp.Skeleton, arg.Skeleton) {
+			t.Errorf("%s: phonetic of %s: got %q %q, want %q %q", step, arg.Identifier,
+				dp.Phonetic.String(), dp.Skeleton.String(), arg.Phonetic.String(), arg.Skeleton.String())
+		}
+
+		if dp.Unvocalized != arg.Unvocalized {
+			t.Errorf("%s: unvocalized of %s: got %v, want %v", step, arg.Identifier, dp.Unvocalized, arg.Unvocalized)
+		}
+	}
+
+	// Scan the phonetics, which must be sorted by ID
+	var scannedIDs []int
+	err = r.ScanDocumentPhonetics(ctx, func(id int, _ index.DocumentPhonetic) bool {
+		scannedIDs = append(scannedIDs, id)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wantIDs := slices.Sorted(maps.Keys(wantDocs)); !slices.Equal(scannedIDs, wantIDs) {
+		t.Errorf("%s: scan phonetics: got %v, want %v", step, scannedIDs, wantIDs)
+	}
+
+	return seenIDs
+}
+
+func sortLocations(locations []index.TokenLocation) {
+	slices.SortFunc(locations, func(a, b index.TokenLocation) int {
+		return cmp.Or(
+			cmp.Compare(a.DocumentID, b.DocumentID),
+			cmp.Compare(a.Token, b.Token),
+			cmp.Compare(a.Start, b.Start),
+			cmp.Compare(a.End, b.End))
+	})
+}
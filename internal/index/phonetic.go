@@ -0,0 +1,99 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// DocumentPhonetic is the phonetic and skeleton of a document that saved at
+// index time, so the query can be aligned against the document without
+// converting its Arabic text again. The unvocalized document doesn't have
+// any phonetic, since its vowels are unknown.
+type DocumentPhonetic struct {
+	Phonetic phonetic.Group
+	Skeleton phonetic.Group
+
+	// Unvocalized is the flag that saved along with the document, as
+	// described in InsertDocumentArg. It decides the tokens of the
+	// document, since the phonetic might be empty for any document.
+	Unvocalized bool
+}
+
+// Group returns the phonetic group that the tokens with the kind are created
+// from, so it can be compared with the query of the same kind.
+func (dp DocumentPhonetic) Group(kind TokenKind) phonetic.Group {
+	switch kind {
+	case RegularToken:
+		return dp.Phonetic
+	case LooseUnvocalizedToken:
+		return phonetic.LooseSkeleton(dp.Skeleton)
+	default:
+		return dp.Skeleton
+	}
+}
+
+// hasTokens reports whether the document is indexed using the tokens with
+// the kind, following DocumentTokens. The skeleton tokens are only used by
+// vocalized document, so the query that searches them must only be made if
+// the index enables the skeleton tokens.
+func (dp DocumentPhonetic) hasTokens(kind TokenKind) bool {
+	switch kind {
+	case RegularToken, SkeletonToken:
+		return !dp.Unvocalized
+	default:
+		return dp.Unvocalized
+	}
+}
+
+// Tokens returns the tokens that created by DocumentTokens when the document
+// is indexed, so its postings can be found without checking every token.
+func (dp DocumentPhonetic) Tokens(skeletonTokens bool) []Token {
+	return DocumentTokens(InsertDocumentArg{
+		Phonetic:    dp.Phonetic,
+		Skeleton:    dp.Skeleton,
+		Unvocalized: dp.Unvocalized,
+	}, skeletonTokens)
+}
+
+// EncodePhonetic encodes the phonetic group into a compact blob. Each rune is
+// saved as uvarint followed by the delta of its position as varint, since the
+// positions are mostly increasing by one or two.
+func EncodePhonetic(group phonetic.Group) []byte {
+	buf := make([]byte, 0, 2*len(group))
+
+	var prevPos int
+	for _, d := range group {
+		buf = binary.AppendUvarint(buf, uint64(d.Rune))
+		buf = binary.AppendVarint(buf, int64(d.Pos-prevPos))
+		prevPos = d.Pos
+	}
+
+	return buf
+}
+
+// DecodePhonetic decodes the blob that created by EncodePhonetic.
+func DecodePhonetic(buf []byte) (phonetic.Group, error) {
+	var group phonetic.Group
+	var prevPos int
+
+	for len(buf) > 0 {
+		r, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid phonetic blob")
+		}
+		buf = buf[n:]
+
+		delta, n := binary.Varint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid phonetic blob")
+		}
+		buf = buf[n:]
+
+		prevPos += int(delta)
+		group = append(group, phonetic.Data{Rune: rune(r), Pos: prevPos})
+	}
+
+	return group, nil
+}
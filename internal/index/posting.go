@@ -0,0 +1,129 @@
+package index
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// Posting is the location of a token in a document.
+type Posting struct {
+	DocumentID int
+	Start      int
+	End        int
+}
+
+// EncodePostings encodes the postings into a compact blob. The postings
+// must be already sorted by document ID and start. Each posting is saved as
+// three uvarints: the delta of document ID, the start (or its delta if it's
+// in the same document as the previous posting) and the length.
+func EncodePostings(postings []Posting) []byte {
+	buf := make([]byte, 0, 4*len(postings))
+
+	var prev Posting
+	for _, p := range postings {
+		buf = AppendPosting(buf, prev, p)
+		prev = p
+	}
+
+	return buf
+}
+
+// AppendPosting appends the posting into the blob that created by
+// EncodePostings, where prev is the last posting in the blob. It's used
+// to split the postings into several blobs while encoding them.
+func AppendPosting(buf []byte, prev, p Posting) []byte {
+	docDelta := p.DocumentID - prev.DocumentID
+	start := p.Start
+	if docDelta == 0 {
+		start -= prev.Start
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(docDelta))
+	buf = binary.AppendUvarint(buf, uint64(start))
+	return binary.AppendUvarint(buf, uint64(p.End-p.Start))
+}
+
+// DecodePostings decodes the blob that created by EncodePostings.
+func DecodePostings(buf []byte) ([]Posting, error) {
+	var postings []Posting
+	err := decodePostings(buf, func(p Posting) bool {
+		postings = append(postings, p)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return postings, nil
+}
+
+// DecodeDocumentPostings decodes only the postings of the document from the
+// blob that created by EncodePostings. Since the postings are sorted by
+// document ID, the decoding stops once it passes the document.
+func DecodeDocumentPostings(buf []byte, documentID int) ([]Posting, error) {
+	var postings []Posting
+	err := decodePostings(buf, func(p Posting) bool {
+		if p.DocumentID == documentID {
+			postings = append(postings, p)
+		}
+		return p.DocumentID <= documentID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return postings, nil
+}
+
+// decodePostings calls fn with each posting in the blob, until fn returns
+// false or the blob is fully decoded.
+func decodePostings(buf []byte, fn func(Posting) bool) error {
+	var prev Posting
+	for len(buf) > 0 {
+		var values [3]uint64
+		for i := range values {
+			v, n := binary.Uvarint(buf)
+			if n <= 0 {
+				return fmt.Errorf("invalid posting blob")
+			}
+			values[i], buf = v, buf[n:]
+		}
+
+		p := Posting{
+			DocumentID: prev.DocumentID + int(values[0]),
+			Start:      int(values[1]),
+		}
+
+		if values[0] == 0 {
+			p.Start += prev.Start
+		}
+
+		p.End = p.Start + int(values[2])
+		if !fn(p) {
+			return nil
+		}
+		prev = p
+	}
+
+	return nil
+}
+
+// SortPostings sorts the postings by document ID, start and end,
+// then removes the duplicate.
+func SortPostings(postings []Posting) []Posting {
+	slices.SortFunc(postings, func(a, b Posting) int {
+		if a.DocumentID != b.DocumentID {
+			return cmp.Compare(a.DocumentID, b.DocumentID)
+		}
+
+		if a.Start != b.Start {
+			return cmp.Compare(a.Start, b.Start)
+		}
+
+		return cmp.Compare(a.End, b.End)
+	})
+
+	return slices.Compact(postings)
+}
@@ -0,0 +1,47 @@
+package index
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDecodeDocumentPostings(t *testing.T) {
+	postings := []Posting{
+		{DocumentID: 1, Start: 0, End: 3},
+		{DocumentID: 3, Start: 2, End: 5},
+		{DocumentID: 3, Start: 7, End: 12},
+		{DocumentID: 5, Start: 4, End: 8},
+	}
+
+	buf := EncodePostings(postings)
+	decoded, err := DecodePostings(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(decoded, postings) {
+		t.Fatalf("decode postings: got %v, want %v", decoded, postings)
+	}
+
+	tests := []struct {
+		documentID int
+		want       []Posting
+	}{
+		{1, postings[:1]},
+		{3, postings[1:3]},
+		{4, nil},
+		{5, postings[3:]},
+		{6, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeDocumentPostings(buf, tt.documentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("decode postings of %d: got %v, want %v", tt.documentID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package index
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/hablullah/go-lafzi/internal/align"
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// alignScoring returns the scoring that used to align the query against the
+// phonetic of document. Since the match is worth one, the alignment score
+// divided by the query length is the confidence of the alignment.
+func alignScoring(confusions ConfusionMatrix) align.Scoring[rune] {
+	scoring := align.Scoring[rune]{Match: 1, Mismatch: -1, Gap: -1}
+	if len(confusions) > 0 {
+		scoring.SubstitutionCost = confusions.Cost
+	}
+	return scoring
+}
+
+// rerankResults re-scores the top results by aligning the query of each
+// matched group against the phonetic of the document, which is more precise
+// than counting the matched tokens. The re-scored results whose confidence is
+// below the minimum are removed, while the rest are sorted and placed before
+// the results that aren't re-scored. The alignment confidence is not on the
+// same scale as the scorer, so the results are only sorted within each part,
+// and the whole results are not sorted by confidence anymore. Returns the
+// number of removed results.
+func rerankResults(ctx context.Context, r Reader, results []SearchResult, queries []TokenQuery, opts SearchOptions) ([]SearchResult, int, error) {
+	// If there are nothing to re-rank, stop early
+	n := min(opts.Rerank, len(results))
+	if n <= 0 {
+		return results, 0, nil
+	}
+
+	// Fetch the phonetic of documents
+	documentIDs := make([]int, n)
+	for i, res := range results[:n] {
+		documentIDs[i] = res.DocumentID
+	}
+
+	phonetics, err := r.DocumentPhonetics(ctx, documentIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Re-score the results
+	reranked := make([]SearchResult, 0, len(results))
+	for _, res := range results[:n] {
+		dp := phonetics[res.DocumentID]
+		res.Confidence, res.Positions = alignGroups(res.groups, dp, queries, opts)
+		if len(res.Positions) > 0 {
+			reranked = append(reranked, res)
+		}
+	}
+
+	nRemoved := n - len(reranked)
+	slices.SortFunc(reranked, compareResult)
+	return append(reranked, results[n:]...), nRemoved, nil
+}
+
+// alignGroups aligns the query of each group against the document phonetic
+// around the group. Returns the best confidence, along with the aligned
+// positions whose confidence reach the minimum.
+func alignGroups(groups []TokenLocationGroup, dp DocumentPhonetic, queries []TokenQuery, opts SearchOptions) (float64, [][2]int) {
+	var confidence float64
+	var positions [][2]int
+	scoring := alignScoring(opts.Confusions)
+	for _, g := range groups {
+		query := queries[g.Query]
+		c, start, end := alignQuery(query, dp.Group(query.Kind), g.Start, g.End, scoring)
+		if c < opts.MinConfidence {
+			continue
+		}
+
+		confidence = max(confidence, c)
+		positions = append(positions, [2]int{start, end})
+	}
+
+	// Several groups might be aligned into the same part
+	slices.SortFunc(positions, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		return cmp.Compare(a[1], b[1])
+	})
+
+	return confidence, slices.Compact(positions)
+}
+
+// alignQuery aligns the query against the document phonetic around the
+// [start, end) range in the original text. Returns the confidence of the
+// alignment and the range of the aligned part in the original text.
+func alignQuery(query TokenQuery, group phonetic.Group, start, end int, scoring align.Scoring[rune]) (float64, int, int) {
+	queryRunes := []rune(query.Text)
+	if len(queryRunes) == 0 {
+		return 0, start, end
+	}
+
+	// Find the phonetic runes within the range
+	first, last := -1, -1
+	for i, d := range group {
+		if d.Pos >= start && d.Pos < end {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first < 0 {
+		return 0, start, end
+	}
+
+	// The tokens might only match a part of the query, so the range
+	// is widened by the query length on both sides.
+	margin := len(queryRunes)
+	window := group[max(first-margin, 0):min(last+1+margin, len(group))]
+	windowRunes := make([]rune, len(window))
+	for i, d := range window {
+		windowRunes[i] = d.Rune
+	}
+
+	// Align the query
+	alignment := align.Local(queryRunes, windowRunes, scoring)
+	if alignment.Score <= 0 {
+		return 0, start, end
+	}
+
+	confidence := alignment.Score / (float64(len(queryRunes)) * scoring.Match)
+	alignedStart, alignedEnd := window[alignment.BStart:alignment.BEnd].Boundary()
+	return confidence, alignedStart, alignedEnd
+}
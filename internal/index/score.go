@@ -0,0 +1,150 @@
+package index
+
+import "math"
+
+const (
+	// defaultIdealGap is the ideal gap between positions of the regular tokens.
+	defaultIdealGap = 3
+
+	// DefaultPartialThreshold and DefaultPartialPenalty are used to penalize
+	// the completeness of match that only has a small part of the query.
+	DefaultPartialThreshold = 0.5
+	DefaultPartialPenalty   = 0.5
+)
+
+// Match is the group of token locations that scored by the custom scorer.
+type Match struct {
+	// Tokens is the index of the matched tokens in its query, while Costs
+	// is the substitution cost of each token if it's matched using the
+	// confused runes. Costs is nil if all tokens are matched exactly.
+	Tokens []int
+	Costs  []float64
+
+	// Positions is the start position of the matched tokens, while Start
+	// and End are the range of the whole match.
+	Positions []int
+	Start     int
+	End       int
+
+	// QueryLength is the number of tokens in the query, while IdealGap is
+	// the ideal gap between token positions for the query.
+	QueryLength int
+	IdealGap    float64
+
+	// IDF is the inverse document frequency of each token in the query.
+	IDF []float64
+
+	// DocumentLength is the number of runes in the document text.
+	DocumentLength int
+}
+
+// Completeness returns the ratio of the matched tokens in the query, which
+// multiplied by the partial penalty if it's not bigger than the threshold.
+func Completeness(m Match, partialThreshold, partialPenalty float64) float64 {
+	matched := float64(len(m.Tokens)) - sumCosts(m.Costs)
+	return calcCompleteness(matched, m.QueryLength, partialThreshold, partialPenalty)
+}
+
+// WeightedCompleteness is the same as Completeness, except each token is
+// weighted by its IDF, so the rare tokens are more important.
+func WeightedCompleteness(m Match, partialThreshold, partialPenalty float64) float64 {
+	if len(m.IDF) == 0 || len(m.IDF) != m.QueryLength {
+		return Completeness(m, partialThreshold, partialPenalty)
+	}
+
+	var matched, total float64
+	for _, idf := range m.IDF {
+		total += idf
+	}
+	for i, token := range m.Tokens {
+		weight := m.IDF[token]
+		if i < len(m.Costs) {
+			weight *= 1 - m.Costs[i]
+		}
+		matched += weight
+	}
+
+	if total <= 0 {
+		return 0
+	}
+	return applyPartialPenalty(matched/total, partialThreshold, partialPenalty)
+}
+
+// Compactness compares the mean gap between the matched token positions
+// with the ideal gap of the query.
+func Compactness(m Match) float64 {
+	return calcCompactness(m.Positions, m.IdealGap)
+}
+
+func calcConfidence(group TokenLocationGroup, opts SearchOptions) float64 {
+	// Use plain product when there are no custom weights
+	completeness, compactness := group.Completeness, group.Compactness
+	if opts.CompletenessWeight > 0 && opts.CompletenessWeight != 1 {
+		completeness = math.Pow(completeness, opts.CompletenessWeight)
+	}
+	if opts.CompactnessWeight > 0 && opts.CompactnessWeight != 1 {
+		compactness = math.Pow(compactness, opts.CompactnessWeight)
+	}
+	return completeness * compactness
+}
+
+func calcCompleteness(matched float64, expectedCount int, partialThreshold, partialPenalty float64) float64 {
+	if expectedCount <= 0 {
+		return 0
+	}
+
+	score := matched / float64(expectedCount)
+	return applyPartialPenalty(score, partialThreshold, partialPenalty)
+}
+
+// sumCosts returns the total substitution cost of the matched tokens.
+func sumCosts(costs []float64) float64 {
+	var sum float64
+	for _, cost := range costs {
+		sum += cost
+	}
+	return sum
+}
+
+// applyPartialPenalty penalizes the completeness when it's too small.
+func applyPartialPenalty(score, partialThreshold, partialPenalty float64) float64 {
+	if score <= partialThreshold {
+		score *= partialPenalty
+	}
+	return score
+}
+
+// calcIDF returns the smoothed inverse document frequency of a token, so
+// the token that not used by any documents still has a finite weight.
+func calcIDF(frequency, nDocument int) float64 {
+	return math.Log(float64(nDocument+1)/float64(frequency+1)) + 1
+}
+
+func calcCompactness(positions []int, idealGap float64) float64 {
+	// Handle edge cases: empty positions or single element
+	// Single elements have no gaps, so they're perfectly compact
+	nPosition := len(positions)
+	if nPosition <= 1 {
+		return 1.0
+	}
+
+	// Calculate gaps average
+	var gapSum int
+	nGap := nPosition - 1
+	for i := range nGap {
+		gapSum += positions[i+1] - positions[i]
+	}
+
+	// Calculate mean of gaps
+	gapMean := float64(gapSum) / float64(nGap)
+	if gapMean == 0 {
+		return 1
+	}
+
+	// Calculate compactness by comparing the mean with ideal gap value. Ideally,
+	// gap between token position is at most 3.
+	if idealGap <= 0 {
+		idealGap = defaultIdealGap
+	}
+	return min(1, idealGap/gapMean)
+}
@@ -0,0 +1,603 @@
+package index
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"slices"
+)
+
+// ctxCheckInterval is the number of iterations between
+// context checks while grouping the token locations.
+const ctxCheckInterval = 1024
+
+type TokenLocationGroup struct {
+	DocumentID   int
+	Query        int
+	LastTokenID  int
+	Start        int
+	End          int
+	Count        int
+	TokenIDs     []int
+	Positions    []int
+	Completeness float64
+	Compactness  float64
+	Confidence   float64
+
+	// Costs is the substitution cost of each token in TokenIDs. It's nil
+	// if all tokens are matched exactly, which is the most common case.
+	Costs []float64
+}
+
+// appendCost appends the substitution cost of the last token in TokenIDs.
+// The costs are only kept once there is a substituted token.
+func (g *TokenLocationGroup) appendCost(cost float64) {
+	if cost == 0 && g.Costs == nil {
+		return
+	}
+
+	if g.Costs == nil {
+		g.Costs = make([]float64, len(g.TokenIDs)-1, len(g.TokenIDs))
+	}
+
+	g.Costs = append(g.Costs, cost)
+}
+
+// lastCost returns the substitution cost of the last token in TokenIDs.
+func (g *TokenLocationGroup) lastCost() float64 {
+	if len(g.Costs) == 0 {
+		return 0
+	}
+	return g.Costs[len(g.Costs)-1]
+}
+
+// maxSubstitutionGap returns the max gap between the substituted token and
+// its neighbour in the same group, which is three times the ideal gap.
+func maxSubstitutionGap(query TokenQuery) float64 {
+	idealGap := query.IdealGap
+	if idealGap <= 0 {
+		idealGap = defaultIdealGap
+	}
+	return 3 * idealGap
+}
+
+// SearchOptions is the options that used while searching tokens.
+type SearchOptions struct {
+	MinConfidence      float64
+	Limit              int
+	Offset             int
+	CompletenessWeight float64
+	CompactnessWeight  float64
+
+	// Scorer calculates the confidence of each match. If it's nil, the
+	// completeness and compactness are multiplied using their weights.
+	Scorer func(Match) float64
+
+	// Rerank is the number of top results that re-scored by aligning the
+	// query against the phonetic of documents. The re-scored results are
+	// placed before the others, as described in rerankResults. If it's zero
+	// or negative, the results are not re-scored.
+	Rerank int
+
+	// Confusions is the cost of substituting the runes that often confused
+	// with each other. If it's not empty, the tokens are also matched with
+	// their confused runes, and the substitution cost is used while scoring.
+	Confusions ConfusionMatrix
+
+	// ShortQueryLimit is the max number of documents that kept when all
+	// queries are too short to have any token. Only the best matches are
+	// kept, which prefer the shorter documents. The single rune query is not
+	// searched. If it's zero or negative, the default 100 will be used.
+	ShortQueryLimit int
+}
+
+// TokenQuery is the tokens that searched together. If several queries are
+// searched at once, each of them is scored separately, then the results
+// are merged for each document.
+type TokenQuery struct {
+	Tokens []int64
+
+	// Kind and Text are the kind of tokens and the phonetic string that
+	// the tokens are created from. They are only used to explain the search.
+	Kind TokenKind
+	Text string
+
+	// IdealGap is the max gap between token positions in a compact match.
+	// If it's zero or negative, the default 3 will be used.
+	IdealGap float64
+}
+
+type SearchResult struct {
+	DocumentID int
+	Identifier string
+	Text       string
+	Confidence float64
+	Positions  [][2]int
+
+	// groups is the matched groups in the document, which kept
+	// so the result can be re-scored later.
+	groups []TokenLocationGroup
+}
+
+// SearchTokens look for document ids which contains the tokens in the queries,
+// then count how many tokens occured in each document. The reader should be
+// created by Index.View, so the whole search sees the same documents.
+// Beside the results, it also returns the total number of matching documents,
+// so the caller can paginate the results using the limit and offset options.
+// The total is only capped for the short queries, which reported by capped.
+func SearchTokens(ctx context.Context, r Reader, opts SearchOptions, queries ...TokenQuery) (results []SearchResult, total int, capped bool, err error) {
+	// If there are no tokens submitted, fallback to the short queries
+	ordinalQueries, tokenOrdinals := mapTokenOrdinals(queries, opts.Confusions)
+	if len(ordinalQueries) == 0 {
+		return searchShortQueries(ctx, r, opts, queries)
+	}
+
+	// Look up all tokens at once
+	locations, err := r.LookupTokens(ctx, distinctTokens(tokenOrdinals))
+	if err != nil {
+		return
+	}
+
+	// Group the token locations
+	flatTokenLocations := flattenLocations(locations, ordinalQueries, tokenOrdinals)
+	scoring, err := fetchScoringData(ctx, r, flatTokenLocations, queries, opts)
+	if err != nil {
+		return
+	}
+
+	groups, err := groupLocations(ctx, flatTokenLocations, ordinalQueries, queries, scoring, opts)
+	if err != nil {
+		return
+	}
+
+	// If there are no groups, stop early
+	nGroups := len(groups)
+	if nGroups == 0 {
+		return
+	}
+
+	// Sort the groups based on document ID and its start
+	slices.SortFunc(groups, func(a, b TokenLocationGroup) int {
+		if a.DocumentID != b.DocumentID {
+			return cmp.Compare(a.DocumentID, b.DocumentID)
+		}
+
+		if a.Start != b.Start {
+			return cmp.Compare(a.Start, b.Start)
+		}
+
+		return -cmp.Compare(a.Confidence, b.Confidence)
+	})
+
+	// Create the final result
+	results = make([]SearchResult, 0, nGroups)
+	firstGroup := groups[0]
+	currentResult := SearchResult{
+		DocumentID: firstGroup.DocumentID,
+		Confidence: firstGroup.Confidence,
+		Positions:  [][2]int{{firstGroup.Start, firstGroup.End}},
+		groups:     []TokenLocationGroup{firstGroup},
+	}
+
+	for i := 1; i < nGroups; i++ {
+		gi := groups[i]
+		giPos := [2]int{gi.Start, gi.End}
+
+		// Same document as before, so merge it
+		if currentResult.DocumentID == gi.DocumentID {
+			currentResult.Confidence = max(currentResult.Confidence, gi.Confidence)
+			currentResult.Positions = append(currentResult.Positions, giPos)
+			currentResult.groups = append(currentResult.groups, gi)
+		} else {
+			// We reach different document, so save the current result
+			results = append(results, currentResult)
+
+			// Reset the value of current
+			currentResult = SearchResult{
+				DocumentID: gi.DocumentID,
+				Confidence: gi.Confidence,
+				Positions:  [][2]int{giPos},
+				groups:     []TokenLocationGroup{gi},
+			}
+		}
+	}
+
+	// Save the leftover result
+	results = append(results, currentResult)
+
+	// Only keep the best results that needed for the requested page.
+	// Since only the top results are kept, there is no need to sort all.
+	total = len(results)
+	nTop := total
+	if opts.Limit > 0 {
+		nTop = min(total, max(opts.Offset, 0)+opts.Limit)
+	}
+
+	// If the results are re-ranked, some of them might be removed, so keep
+	// more results to fill the page.
+	if opts.Rerank <= 0 {
+		results = topResults(results, nTop)
+	} else {
+		var nRemoved int
+		results = topResults(results, min(total, nTop+opts.Rerank))
+		results, nRemoved, err = rerankResults(ctx, r, results, queries, opts)
+		if err != nil {
+			return
+		}
+
+		total -= nRemoved
+		results = results[:min(nTop, len(results))]
+	}
+
+	// Apply offset, so only the requested page is fetched
+	if opts.Offset > 0 {
+		results = results[min(opts.Offset, len(results)):]
+	}
+
+	// Fetch document data
+	results, err = fetchDocuments(ctx, r, results)
+	return
+}
+
+// tokenOrdinal is the ordinal of query token that matched by a token. If the
+// token is a variant of the query token with confused rune, the cost is the
+// cost of the substitution.
+type tokenOrdinal struct {
+	ordinal int
+	cost    float64
+}
+
+// mapTokenOrdinals maps each distinct token code to its ordinals in the
+// queries. Same token might occur several times in the query, e.g. "ala" in
+// "xalalah". The ordinals are counted through all queries, so each ordinal
+// belongs to exactly one query, which is saved in ordinalQueries. If there
+// are confusions, the variants of each token are mapped to its ordinal too.
+func mapTokenOrdinals(queries []TokenQuery, confusions ConfusionMatrix) (ordinalQueries []int, tokenOrdinals map[int64][]tokenOrdinal) {
+	tokenOrdinals = make(map[int64][]tokenOrdinal)
+	for i, query := range queries {
+		for _, token := range query.Tokens {
+			ordinal := len(ordinalQueries)
+			tokenOrdinals[token] = append(tokenOrdinals[token], tokenOrdinal{ordinal: ordinal})
+			for variant, cost := range confusions.tokenVariants(token) {
+				tokenOrdinals[variant] = append(tokenOrdinals[variant], tokenOrdinal{ordinal, cost})
+			}
+			ordinalQueries = append(ordinalQueries, i)
+		}
+	}
+	return
+}
+
+func distinctTokens(tokenOrdinals map[int64][]tokenOrdinal) []int64 {
+	tokens := make([]int64, 0, len(tokenOrdinals))
+	for token := range tokenOrdinals {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// flattenLocations duplicates the token location for each ordinal that uses
+// the token, then sort them by document, query and position so they can be
+// grouped. The duplicate locations of the same token within the same query are
+// removed, where the exact match is preferred over the substituted one. The
+// different tokens might share a location, e.g. the tokens of ligature "ﷺ", so
+// all of them are kept.
+func flattenLocations(locations []TokenLocation, ordinalQueries []int, tokenOrdinals map[int64][]tokenOrdinal) []TokenLocation {
+	var flatTokenLocations []TokenLocation
+	for _, tl := range locations {
+		for _, to := range tokenOrdinals[tl.Token] {
+			tl.TokenID = to.ordinal
+			tl.Cost = to.cost
+			flatTokenLocations = append(flatTokenLocations, tl)
+		}
+	}
+
+	if len(flatTokenLocations) == 0 {
+		return nil
+	}
+
+	// Sort the flattened token locations
+	slices.SortFunc(flatTokenLocations, func(a, b TokenLocation) int {
+		if a.DocumentID != b.DocumentID {
+			return cmp.Compare(a.DocumentID, b.DocumentID)
+		}
+
+		aQuery, bQuery := ordinalQueries[a.TokenID], ordinalQueries[b.TokenID]
+		if aQuery != bQuery {
+			return cmp.Compare(aQuery, bQuery)
+		}
+
+		if a.Start != b.Start {
+			return cmp.Compare(a.Start, b.Start)
+		}
+
+		if a.Cost != b.Cost {
+			return cmp.Compare(a.Cost, b.Cost)
+		}
+
+		return cmp.Compare(a.TokenID, b.TokenID)
+	})
+
+	// Compact the sorted token locations. The locations that start at the same
+	// position are adjacent, but the duplicates of a token might be separated
+	// by the other tokens, so the whole run of that position is checked.
+	compacted := flatTokenLocations[:1]
+	var runStart int
+	for _, tl := range flatTokenLocations[1:] {
+		first := compacted[runStart]
+		isSameRun := tl.DocumentID == first.DocumentID &&
+			ordinalQueries[tl.TokenID] == ordinalQueries[first.TokenID] &&
+			tl.Start == first.Start
+
+		if !isSameRun {
+			runStart = len(compacted)
+		} else if slices.ContainsFunc(compacted[runStart:], func(kept TokenLocation) bool {
+			return kept.Token == tl.Token && kept.End == tl.End
+		}) {
+			continue
+		}
+
+		compacted = append(compacted, tl)
+	}
+
+	return compacted
+}
+
+// groupLocations groups the consecutive token locations that sorted by
+// flattenLocations, then score each group. Only groups whose confidence
+// reach the minimum confidence are returned.
+func groupLocations(ctx context.Context, flatTokenLocations []TokenLocation, ordinalQueries []int, queries []TokenQuery, scoring scoringData, opts SearchOptions) ([]TokenLocationGroup, error) {
+	// If there are no tokens, stop
+	nTokenLocations := len(flatTokenLocations)
+	if nTokenLocations == 0 {
+		return nil, nil
+	}
+
+	// The first ordinal of each query, used to find the index of token
+	// within its query.
+	firstOrdinals := queryFirstOrdinals(ordinalQueries, len(queries))
+	scoreGroup := func(group *TokenLocationGroup) {
+		query := queries[group.Query]
+		matched := float64(group.Count) - sumCosts(group.Costs)
+		group.Completeness = calcCompleteness(matched, len(query.Tokens), DefaultPartialThreshold, DefaultPartialPenalty)
+		group.Compactness = calcCompactness(group.Positions, query.IdealGap)
+		if opts.Scorer == nil {
+			group.Confidence = calcConfidence(*group, opts)
+			return
+		}
+
+		tokenIDs := make([]int, len(group.TokenIDs))
+		for i, ordinal := range group.TokenIDs {
+			tokenIDs[i] = ordinal - firstOrdinals[group.Query]
+		}
+
+		group.Confidence = opts.Scorer(Match{
+			Tokens:         tokenIDs,
+			Costs:          group.Costs,
+			Positions:      group.Positions,
+			Start:          group.Start,
+			End:            group.End,
+			QueryLength:    len(query.Tokens),
+			IdealGap:       query.IdealGap,
+			IDF:            scoring.queryIDF[group.Query],
+			DocumentLength: scoring.documentLengths[group.DocumentID],
+		})
+	}
+
+	// Create group from token locations
+	groups := make([]TokenLocationGroup, 0, nTokenLocations)
+	firstTL := flatTokenLocations[0]
+	currentGroup := TokenLocationGroup{
+		DocumentID:  firstTL.DocumentID,
+		Query:       ordinalQueries[firstTL.TokenID],
+		LastTokenID: firstTL.TokenID,
+		Start:       firstTL.Start,
+		End:         firstTL.End,
+		Count:       1,
+		TokenIDs:    []int{firstTL.TokenID},
+		Positions:   []int{firstTL.Start},
+	}
+	currentGroup.appendCost(firstTL.Cost)
+
+	for i := 1; i < nTokenLocations; i++ {
+		// Periodically check if the search is already cancelled
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		tl := flatTokenLocations[i]
+		tlQuery := ordinalQueries[tl.TokenID]
+		isSameQuery := tl.DocumentID == currentGroup.DocumentID &&
+			tlQuery == currentGroup.Query
+		isSameGroup := isSameQuery && tl.TokenID > currentGroup.LastTokenID
+
+		// The substituted token is less reliable than the exact one, so it
+		// only joins the group if it's close to its neighbour, and it's not
+		// allowed to split the group.
+		if isSameGroup && (tl.Cost > 0 || currentGroup.lastCost() > 0) {
+			lastPosition := currentGroup.Positions[len(currentGroup.Positions)-1]
+			isSameGroup = float64(tl.Start-lastPosition) <= maxSubstitutionGap(queries[tlQuery])
+		}
+
+		if isSameQuery && !isSameGroup && tl.Cost > 0 {
+			continue
+		}
+
+		if isSameGroup {
+			currentGroup.Count++
+			currentGroup.End = tl.End
+			currentGroup.LastTokenID = tl.TokenID
+			currentGroup.TokenIDs = append(currentGroup.TokenIDs, tl.TokenID)
+			currentGroup.Positions = append(currentGroup.Positions, tl.Start)
+			currentGroup.appendCost(tl.Cost)
+		} else {
+			// We landed on a new group, so save the current one
+			scoreGroup(&currentGroup)
+			if currentGroup.Confidence >= opts.MinConfidence {
+				groups = append(groups, currentGroup)
+			}
+
+			// Once saved, reset the current group with the current token
+			currentGroup = TokenLocationGroup{
+				DocumentID:  tl.DocumentID,
+				Query:       tlQuery,
+				LastTokenID: tl.TokenID,
+				Start:       tl.Start,
+				End:         tl.End,
+				Count:       1,
+				TokenIDs:    []int{tl.TokenID},
+				Positions:   []int{tl.Start},
+			}
+			currentGroup.appendCost(tl.Cost)
+		}
+	}
+
+	// Save the last group
+	scoreGroup(&currentGroup)
+	if currentGroup.Confidence >= opts.MinConfidence {
+		groups = append(groups, currentGroup)
+	}
+
+	return groups, nil
+}
+
+// queryFirstOrdinals returns the first ordinal of each query.
+func queryFirstOrdinals(ordinalQueries []int, nQueries int) []int {
+	firstOrdinals := make([]int, nQueries)
+	for i := len(ordinalQueries) - 1; i >= 0; i-- {
+		firstOrdinals[ordinalQueries[i]] = i
+	}
+	return firstOrdinals
+}
+
+// scoringData is the data that only needed by the custom scorer.
+type scoringData struct {
+	documentLengths map[int]int
+	queryIDF        [][]float64
+}
+
+// fetchScoringData fetch the length of documents in the token locations and
+// the IDF of tokens in each query. They are only needed by the custom scorer,
+// so if there is no custom scorer nothing is fetched.
+func fetchScoringData(ctx context.Context, r Reader, flatTokenLocations []TokenLocation, queries []TokenQuery, opts SearchOptions) (data scoringData, err error) {
+	if opts.Scorer == nil || len(flatTokenLocations) == 0 {
+		return
+	}
+
+	// The locations are sorted by document, so simply skip the same ID
+	var documentIDs []int
+	for _, tl := range flatTokenLocations {
+		if n := len(documentIDs); n == 0 || documentIDs[n-1] != tl.DocumentID {
+			documentIDs = append(documentIDs, tl.DocumentID)
+		}
+	}
+
+	data.documentLengths, err = r.DocumentLengths(ctx, documentIDs)
+	if err != nil {
+		return
+	}
+
+	// Calculate the IDF of each query token
+	var tokens []int64
+	for _, query := range queries {
+		tokens = append(tokens, query.Tokens...)
+	}
+
+	frequencies, nDocument, err := r.TokenFrequencies(ctx, tokens)
+	if err != nil {
+		return
+	}
+
+	data.queryIDF = make([][]float64, len(queries))
+	for i, query := range queries {
+		data.queryIDF[i] = make([]float64, len(query.Tokens))
+		for j, token := range query.Tokens {
+			data.queryIDF[i][j] = calcIDF(frequencies[token], nDocument)
+		}
+	}
+
+	return
+}
+
+// fetchDocuments fetch the identifier and text for each search result.
+// Since the reader sees the same documents whose tokens are looked up,
+// every result has its document.
+func fetchDocuments(ctx context.Context, r Reader, results []SearchResult) ([]SearchResult, error) {
+	// If there are no results, stop early
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// Fetch the documents
+	documentIDs := make([]int, len(results))
+	for i, res := range results {
+		documentIDs[i] = res.DocumentID
+	}
+
+	docs, err := r.FetchDocuments(ctx, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	mapDocs := make(map[int]Document, len(docs))
+	for _, doc := range docs {
+		mapDocs[doc.ID] = doc
+	}
+
+	// Fill the results with the document data
+	for i, res := range results {
+		doc := mapDocs[res.DocumentID]
+		results[i].Identifier = doc.Identifier
+		results[i].Text = doc.Arabic
+	}
+
+	return results, nil
+}
+
+// topResults returns n results with the best confidence, sorted from the best.
+func topResults(results []SearchResult, n int) []SearchResult {
+	// If all results are needed, simply sort them
+	if n >= len(results) {
+		slices.SortFunc(results, compareResult)
+		return results
+	}
+
+	// Use min-heap to keep the n best results, where the worst
+	// of them is located on the root.
+	h := resultHeap(make([]SearchResult, 0, n))
+	for _, res := range results {
+		if len(h) < n {
+			heap.Push(&h, res)
+		} else if n > 0 && compareResult(res, h[0]) < 0 {
+			h[0] = res
+			heap.Fix(&h, 0)
+		}
+	}
+
+	slices.SortFunc(h, compareResult)
+	return h
+}
+
+// compareResult compare two results, where the better one is sorted first.
+func compareResult(a, b SearchResult) int {
+	if a.Confidence != b.Confidence {
+		return -cmp.Compare(a.Confidence, b.Confidence)
+	}
+
+	return cmp.Compare(a.DocumentID, b.DocumentID)
+}
+
+type resultHeap []SearchResult
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return compareResult(h[i], h[j]) > 0 }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x any)        { *h = append(*h, x.(SearchResult)) }
+func (h *resultHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
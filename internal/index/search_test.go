@@ -0,0 +1,80 @@
+package index_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// regularQuery creates the query for the phonetic of Arabic text.
+func regularQuery(arabic string) index.TokenQuery {
+	text := phonetic.FromArabic(arabic).String()
+	query := index.TokenQuery{Kind: index.RegularToken, Text: text}
+	for _, ngram := range phonetic.NGrams(text, 3) {
+		query.Tokens = append(query.Tokens, index.EncodeToken(index.RegularToken, ngram))
+	}
+	return query
+}
+
+func TestSearchTokensConcurrentDelete(t *testing.T) {
+	args := []index.InsertDocumentArg{
+		index.NewInsertDocumentArg("ikhlas-1", "قُلْ هُوَ اللَّهُ أَحَدٌ", false),
+		index.NewInsertDocumentArg("ikhlas-2", "قُلْ هُوَ اللَّهُ أَحَدٌ", false),
+		index.NewInsertDocumentArg("ikhlas-3", "قُلْ هُوَ اللَّهُ أَحَدٌ", false),
+	}
+
+	tests := []struct {
+		name  string
+		query index.TokenQuery
+	}{
+		{"regular", regularQuery("قُلْ هُوَ اللَّهُ أَحَدٌ")},
+		{"short", index.TokenQuery{Kind: index.RegularToken, Text: "hu"}},
+	}
+
+	for _, ti := range testIndexes {
+		t.Run(ti.name, func(t *testing.T) {
+			idx, _ := ti.open(t)
+			defer idx.Close()
+
+			ctx := context.Background()
+			for _, tt := range tests {
+				// Add the document back, so every query sees all of them
+				if err := idx.InsertDocuments(ctx, args...); err != nil {
+					t.Fatal(err)
+				}
+
+				// Remove a document while searching. Whether the search
+				// sees it or not, the results must match the total.
+				var results []index.SearchResult
+				var total int
+				deleted := make(chan error, 1)
+				err := idx.View(ctx, func(r index.Reader) (err error) {
+					go func() { deleted <- idx.DeleteDocuments(ctx, "ikhlas-2") }()
+
+					opts := index.SearchOptions{MinConfidence: 0.4}
+					results, total, _, err = index.SearchTokens(ctx, r, opts, tt.query)
+					return
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := <-deleted; err != nil {
+					t.Fatal(err)
+				}
+
+				if len(results) != total || total < 2 {
+					t.Errorf("%s: got %d results and total %d", tt.name, len(results), total)
+				}
+
+				for _, res := range results {
+					if res.Identifier == "" {
+						t.Errorf("%s: result %d has no document", tt.name, res.DocumentID)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package index
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// defaultShortQueryLimit is the max number of documents that
+// matched by the short queries, if the limit is not specified.
+const defaultShortQueryLimit = 100
+
+// minShortQueryLength is the min number of runes in the short query. The
+// single rune is found in almost every document, so it's not searched.
+const minShortQueryLength = 2
+
+// shortQueryCoverageWeight is the exponent that applied to the coverage of
+// the short query, the same as the default weight of coverage in scorer.
+const shortQueryCoverageWeight = 0.2
+
+// isShortQuery reports whether the query text is too short to be split
+// into any token, e.g. "la" or "hu" which only have two runes. The query
+// that shorter than minShortQueryLength is not searched at all.
+func isShortQuery(query TokenQuery) bool {
+	return len(query.Tokens) == 0 &&
+		utf8.RuneCountInString(query.Text) >= minShortQueryLength
+}
+
+// searchShortQueries is the fallback of SearchTokens when none of the queries
+// has any token. Since there are no postings for the short queries, they are
+// matched by scanning the phonetic of every document. The short query is
+// found in most documents, so only the best matches up to the limit are kept,
+// and the total is capped by the limit too. Since nothing is better than the
+// exact match of the whole document, the scan stops early once all kept
+// matches have full confidence. Beside the results, it reports whether the
+// total is capped, i.e. there might be more matching documents than the
+// limit. The query that shorter than minShortQueryLength doesn't scan the
+// documents at all.
+func searchShortQueries(ctx context.Context, r Reader, opts SearchOptions, queries []TokenQuery) (results []SearchResult, total int, capped bool, err error) {
+	// If there are no short queries, stop early
+	if !slices.ContainsFunc(queries, isShortQuery) {
+		return
+	}
+
+	limit := opts.ShortQueryLimit
+	if limit <= 0 {
+		limit = defaultShortQueryLimit
+	}
+
+	// Scan the documents while keeping the best results in a min-heap, so
+	// the worst of the kept results is always at the root
+	h := make(resultHeap, 0, limit)
+	err = r.ScanDocumentPhonetics(ctx, func(id int, dp DocumentPhonetic) bool {
+		confidence, positions := matchShortQueries(queries, dp, opts)
+		if len(positions) == 0 {
+			return true
+		}
+
+		result := SearchResult{
+			DocumentID: id,
+			Confidence: confidence,
+			Positions:  positions,
+		}
+
+		switch {
+		case len(h) < limit:
+			heap.Push(&h, result)
+		case compareResult(result, h[0]) < 0:
+			capped = true
+			h[0] = result
+			heap.Fix(&h, 0)
+		default:
+			capped = true
+		}
+
+		// The documents are scanned by their ID, so once all kept results have
+		// full confidence, the next documents can't replace them
+		if len(h) == limit && h[0].Confidence >= 1 {
+			capped = true
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	// Sort the kept results, then apply limit and offset
+	results = []SearchResult(h)
+	slices.SortFunc(results, compareResult)
+	total = len(results)
+	if opts.Offset > 0 {
+		results = results[min(opts.Offset, len(results)):]
+	}
+
+	if opts.Limit > 0 {
+		results = results[:min(opts.Limit, len(results))]
+	}
+
+	// Fetch document data
+	results, err = fetchDocuments(ctx, r, results)
+	return
+}
+
+// matchShortQueries looks for every occurrence of the short queries in the
+// document phonetic. The confidence of an occurrence is the ratio of runes
+// that matched exactly, where the confused runes are counted by their cost.
+// It's multiplied by the weighted coverage of the query in the document, so
+// the short query only has full confidence in the document that consists of
+// the query alone, and the longer documents are ranked after it. Returns the best confidence, along with the positions of occurrences
+// whose confidence reach the minimum.
+func matchShortQueries(queries []TokenQuery, dp DocumentPhonetic, opts SearchOptions) (float64, [][2]int) {
+	var confidence float64
+	var positions [][2]int
+	for _, query := range queries {
+		if !isShortQuery(query) || !dp.hasTokens(query.Kind) {
+			continue
+		}
+
+		queryRunes := []rune(query.Text)
+		group := dp.Group(query.Kind)
+		if len(queryRunes) > len(group) {
+			continue
+		}
+
+		coverage := float64(len(queryRunes)) / float64(len(group))
+		coverage = math.Pow(coverage, shortQueryCoverageWeight)
+		for i := 0; i+len(queryRunes) <= len(group); i++ {
+			c := coverage * shortQueryConfidence(queryRunes, group[i:i+len(queryRunes)], opts.Confusions)
+			if c <= 0 || c < opts.MinConfidence {
+				continue
+			}
+
+			start, end := group[i : i+len(queryRunes)].Boundary()
+			confidence = max(confidence, c)
+			positions = append(positions, [2]int{start, end})
+		}
+	}
+
+	// Several queries might match the same part
+	slices.SortFunc(positions, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		return cmp.Compare(a[1], b[1])
+	})
+
+	return confidence, slices.Compact(positions)
+}
+
+// shortQueryConfidence compares the query runes with the phonetic of the same
+// length. Returns zero if any of the runes is completely different.
+func shortQueryConfidence(queryRunes []rune, group phonetic.Group, confusions ConfusionMatrix) float64 {
+	var cost float64
+	for i, r := range queryRunes {
+		c := confusions.Cost(r, group[i].Rune)
+		if c >= 1 {
+			return 0
+		}
+		cost += c
+	}
+
+	return 1 - cost/float64(len(queryRunes))
+}
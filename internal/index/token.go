@@ -0,0 +1,163 @@
+package index
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+// TokenKind is the kind of token. It's saved in the high bits of the token
+// code, so tokens with different kind never share the same code.
+type TokenKind int64
+
+const (
+	// RegularToken is the n-gram of the phonetic.
+	RegularToken TokenKind = iota
+
+	// SkeletonToken is the n-gram of the consonant skeleton of vocalized
+	// document, which is used for matching the query that written without
+	// harakat. It's only indexed if the index enables the skeleton tokens.
+	SkeletonToken
+
+	// UnvocalizedToken is the n-gram of the consonant skeleton of document
+	// that written without harakat. It's separated from SkeletonToken so
+	// the query with vowels can be matched only to the unvocalized documents.
+	UnvocalizedToken
+
+	// LooseUnvocalizedToken is the same as UnvocalizedToken, except it's
+	// created from the loose skeleton which doesn't have any noon.
+	LooseUnvocalizedToken
+)
+
+// String returns the name of the token kind.
+func (k TokenKind) String() string {
+	switch k {
+	case RegularToken:
+		return "regular"
+	case SkeletonToken:
+		return "skeleton"
+	case UnvocalizedToken:
+		return "unvocalized"
+	case LooseUnvocalizedToken:
+		return "loose unvocalized"
+	default:
+		return fmt.Sprintf("kind %d", int64(k))
+	}
+}
+
+// CodeRange returns the range of token code that has the kind,
+// where the end is exclusive.
+func (k TokenKind) CodeRange() (start, end int64) {
+	return int64(k) << tokenKindShift, int64(k+1) << tokenKindShift
+}
+
+// tokenKindShift is the position of token kind in the token code. The n-gram
+// code from encodeNGram uses at most 60 bits, so it's safe.
+const tokenKindShift = 60
+
+// ngramCodeBits is the number of bits that used to encode a single rune.
+const ngramCodeBits = 5
+
+// alphabetCodes maps the rune in phonetic alphabet into its code. Code 0 is
+// reserved for invalid rune, so n-grams with different size will never
+// share the same code. Since the code is saved in the index, it depends on
+// the order of runes in the alphabet, which is never changed.
+var alphabetCodes = func() [128]int64 {
+	var codes [128]int64
+	for i, r := range phonetic.Alphabet {
+		codes[r] = int64(i + 1)
+	}
+	return codes
+}()
+
+// encodeNGram encodes the n-gram into an integer, which is smaller and faster
+// to compare than string. Returns 0 if the n-gram is empty, too long (more
+// than 12 runes) or contains rune that not part of phonetic alphabet.
+func encodeNGram(s string) int64 {
+	if s == "" || len(s) > 63/ngramCodeBits {
+		return 0
+	}
+
+	var code int64
+	for _, r := range s {
+		if r >= 128 || alphabetCodes[r] == 0 {
+			return 0
+		}
+		code = code<<ngramCodeBits | alphabetCodes[r]
+	}
+
+	return code
+}
+
+// decodeNGram decodes the integer that created by encodeNGram
+// back into the n-gram string.
+func decodeNGram(code int64) string {
+	var runes []rune
+	for ; code > 0; code >>= ngramCodeBits {
+		idx := code&(1<<ngramCodeBits-1) - 1
+		if idx < 0 || int(idx) >= len(phonetic.Alphabet) {
+			return ""
+		}
+		runes = append(runes, rune(phonetic.Alphabet[idx]))
+	}
+
+	slices.Reverse(runes)
+	return string(runes)
+}
+
+// Token is the encoded n-gram and its position in the document.
+type Token struct {
+	Code  int64
+	Start int
+	End   int
+}
+
+// EncodeToken encodes the n-gram into token code with the specified kind.
+// Returns 0 if the n-gram is not valid.
+func EncodeToken(kind TokenKind, ngram string) int64 {
+	code := encodeNGram(ngram)
+	if code == 0 {
+		return 0
+	}
+	return int64(kind)<<tokenKindShift | code
+}
+
+// DecodeToken decodes the token code that created by EncodeToken
+// back into its kind and n-gram.
+func DecodeToken(code int64) (TokenKind, string) {
+	kind := TokenKind(code >> tokenKindShift)
+	ngram := decodeNGram(code & (1<<tokenKindShift - 1))
+	return kind, ngram
+}
+
+// Tokens splits the phonetic group into trigram tokens with the specified kind.
+func Tokens(kind TokenKind, group phonetic.Group) []Token {
+	ngrams := group.Split(3)
+	tokens := make([]Token, len(ngrams))
+	for i, ngram := range ngrams {
+		tokens[i] = Token{
+			Code:  EncodeToken(kind, ngram.Text),
+			Start: ngram.Start,
+			End:   ngram.End,
+		}
+	}
+	return tokens
+}
+
+// DocumentTokens returns all tokens that must be indexed for the document.
+// The phonetic of unvocalized document doesn't have any vowels, so only
+// its skeleton is indexed. The skeleton of vocalized document is only
+// indexed if skeletonTokens is true, since it makes the index far bigger.
+func DocumentTokens(arg InsertDocumentArg, skeletonTokens bool) []Token {
+	if arg.Unvocalized {
+		tokens := Tokens(UnvocalizedToken, arg.Skeleton)
+		return append(tokens, Tokens(LooseUnvocalizedToken, phonetic.LooseSkeleton(arg.Skeleton))...)
+	}
+
+	tokens := Tokens(RegularToken, arg.Phonetic)
+	if skeletonTokens {
+		tokens = append(tokens, Tokens(SkeletonToken, arg.Skeleton)...)
+	}
+	return tokens
+}
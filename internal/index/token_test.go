@@ -0,0 +1,77 @@
+package index
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+func TestEncodeToken(t *testing.T) {
+	tests := []struct {
+		kind  TokenKind
+		ngram string
+		valid bool
+	}{
+		{RegularToken, "bis", true},
+		{SkeletonToken, "bsm", true},
+		{UnvocalizedToken, "qzy", true},
+		{LooseUnvocalizedToken, "x0c", true},
+		{RegularToken, "", false},
+		{RegularToken, "abc!", false},
+		{RegularToken, "bismilahirahm", false},
+	}
+
+	for _, tt := range tests {
+		code := EncodeToken(tt.kind, tt.ngram)
+		if valid := code != 0; valid != tt.valid {
+			t.Errorf("EncodeToken(%v, %q) = %d, valid %v", tt.kind, tt.ngram, code, valid)
+			continue
+		}
+
+		if !tt.valid {
+			continue
+		}
+
+		kind, ngram := DecodeToken(code)
+		if kind != tt.kind || ngram != tt.ngram {
+			t.Errorf("DecodeToken(%d) = %v %q, want %v %q", code, kind, ngram, tt.kind, tt.ngram)
+		}
+	}
+
+	// The code of regular token must never change, since it's saved in the index
+	if code := EncodeToken(RegularToken, "bis"); code != 13<<10|19<<5|4 {
+		t.Errorf("EncodeToken(regular, \"bis\") = %d", code)
+	}
+}
+
+func TestDocumentPhoneticTokens(t *testing.T) {
+	skeleton := phonetic.SkeletonFromArabic("بسم الله")
+	tests := []struct {
+		name string
+		dp   DocumentPhonetic
+		want []Token
+	}{
+		{"vocalized", DocumentPhonetic{Skeleton: skeleton},
+			Tokens(SkeletonToken, skeleton)},
+		{"unvocalized", DocumentPhonetic{Skeleton: skeleton, Unvocalized: true},
+			append(Tokens(UnvocalizedToken, skeleton),
+				Tokens(LooseUnvocalizedToken, phonetic.LooseSkeleton(skeleton))...)},
+	}
+
+	// The vocalized document whose phonetic is empty must not be treated
+	// as unvocalized, so it only has the skeleton tokens.
+	for _, tt := range tests {
+		if got := tt.dp.Tokens(true); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %d tokens, want %d", tt.name, len(got), len(tt.want))
+		}
+
+		if got := tt.dp.hasTokens(SkeletonToken); got == tt.dp.Unvocalized {
+			t.Errorf("%s: has skeleton tokens %v, want %v", tt.name, got, !tt.dp.Unvocalized)
+		}
+
+		if got := tt.dp.hasTokens(UnvocalizedToken); got != tt.dp.Unvocalized {
+			t.Errorf("%s: has unvocalized tokens %v, want %v", tt.name, got, tt.dp.Unvocalized)
+		}
+	}
+}
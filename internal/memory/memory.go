@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+// Index is the in-memory index for documents and its tokens.
+// It's safe to be used concurrently.
+type Index struct {
+	mu          sync.RWMutex
+	lastID      int
+	documents   map[int]index.Document
+	identifiers map[string]int
+	docTokens   map[int][]int64
+	postings    map[int64][]index.Posting
+	frequencies map[int64]int
+	phonetics   map[int]encodedPhonetic
+
+	// skeletonTokens is true if the skeleton tokens of vocalized
+	// documents are indexed, as described in index.DocumentTokens.
+	skeletonTokens bool
+}
+
+// encodedPhonetic is the phonetic and skeleton of a document that encoded
+// using index.EncodePhonetic, which is far smaller than the decoded one,
+// along with the flag whether the document is unvocalized.
+type encodedPhonetic struct {
+	phonetic    []byte
+	skeleton    []byte
+	unvocalized bool
+}
+
+// decode decodes the phonetic and skeleton of the document.
+func (ep encodedPhonetic) decode() (dp index.DocumentPhonetic, err error) {
+	dp.Unvocalized = ep.unvocalized
+	if dp.Phonetic, err = index.DecodePhonetic(ep.phonetic); err != nil {
+		return
+	}
+
+	dp.Skeleton, err = index.DecodePhonetic(ep.skeleton)
+	return
+}
+
+var _ index.Index = (*Index)(nil)
+
+// New returns a new empty in-memory index. If skeletonTokens is true, the
+// skeleton tokens of vocalized documents are indexed as well.
+func New(skeletonTokens bool) *Index {
+	return &Index{
+		documents:      make(map[int]index.Document),
+		identifiers:    make(map[string]int),
+		docTokens:      make(map[int][]int64),
+		postings:       make(map[int64][]index.Posting),
+		frequencies:    make(map[int64]int),
+		phonetics:      make(map[int]encodedPhonetic),
+		skeletonTokens: skeletonTokens,
+	}
+}
+
+// InsertDocuments save the documents into the index. Since it's fast, the
+// context is only checked once at the start so the documents are either all
+// saved or none at all.
+func (idx *Index) InsertDocuments(ctx context.Context, args ...index.InsertDocumentArg) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, arg := range args {
+		// Get document ID if it's exist, and remove its old tokens
+		documentID, exist := idx.identifiers[arg.Identifier]
+		if exist {
+			idx.removeTokens(documentID)
+		} else {
+			idx.lastID++
+			documentID = idx.lastID
+			idx.identifiers[arg.Identifier] = documentID
+		}
+
+		// Save document
+		idx.documents[documentID] = index.Document{
+			ID:         documentID,
+			Identifier: arg.Identifier,
+			Arabic:     arg.Arabic,
+		}
+
+		// Save phonetic and tokens
+		idx.phonetics[documentID] = encodeDocumentPhonetic(arg)
+		idx.addTokens(documentID, index.DocumentTokens(arg, idx.skeletonTokens))
+	}
+
+	return nil
+}
+
+// DeleteDocuments remove the documents from the index.
+func (idx *Index) DeleteDocuments(ctx context.Context, identifiers ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, identifier := range identifiers {
+		documentID, exist := idx.identifiers[identifier]
+		if !exist {
+			continue
+		}
+
+		idx.removeTokens(documentID)
+		delete(idx.documents, documentID)
+		delete(idx.identifiers, identifier)
+		delete(idx.phonetics, documentID)
+	}
+
+	return nil
+}
+
+// encodeDocumentPhonetic encodes the phonetic and skeleton of the document.
+func encodeDocumentPhonetic(arg index.InsertDocumentArg) encodedPhonetic {
+	return encodedPhonetic{
+		phonetic:    index.EncodePhonetic(arg.Phonetic),
+		skeleton:    index.EncodePhonetic(arg.Skeleton),
+		unvocalized: arg.Unvocalized,
+	}
+}
+
+// addTokens save the tokens as postings of the document. The document must
+// not have any tokens yet, i.e. its old tokens must be removed first.
+func (idx *Index) addTokens(documentID int, tokens []index.Token) {
+	codes := make([]int64, 0, len(tokens))
+	for _, token := range tokens {
+		codes = append(codes, token.Code)
+		idx.postings[token.Code] = append(idx.postings[token.Code], index.Posting{
+			DocumentID: documentID,
+			Start:      token.Start,
+			End:        token.End,
+		})
+	}
+
+	slices.Sort(codes)
+	codes = slices.Compact(codes)
+	for _, code := range codes {
+		idx.frequencies[code]++
+	}
+
+	idx.docTokens[documentID] = codes
+}
+
+// removeTokens remove all postings that belong to the document.
+func (idx *Index) removeTokens(documentID int) {
+	for _, token := range idx.docTokens[documentID] {
+		postings := slices.DeleteFunc(idx.postings[token], func(p index.Posting) bool {
+			return p.DocumentID == documentID
+		})
+
+		if len(postings) == 0 {
+			delete(idx.postings, token)
+			delete(idx.frequencies, token)
+		} else {
+			idx.postings[token] = postings
+			idx.frequencies[token]--
+		}
+	}
+
+	delete(idx.docTokens, documentID)
+}
+
+// View calls fn with the reader of the index, which is locked for reading
+// until fn returns so the documents can't be changed in the meantime.
+func (idx *Index) View(ctx context.Context, fn func(r index.Reader) error) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return fn((*reader)(idx))
+}
+
+// reader reads the index without locking it, since it's only used
+// within View while the index is already locked.
+type reader Index
+
+var _ index.Reader = (*reader)(nil)
+
+// LookupTokens returns the locations of the tokens in all documents.
+func (idx *reader) LookupTokens(ctx context.Context, tokens []int64) ([]index.TokenLocation, error) {
+	var locations []index.TokenLocation
+	for _, token := range tokens {
+		for _, p := range idx.postings[token] {
+			locations = append(locations, index.TokenLocation{
+				DocumentID: p.DocumentID,
+				Token:      token,
+				Start:      p.Start,
+				End:        p.End,
+			})
+		}
+	}
+
+	return locations, ctx.Err()
+}
+
+// LookupDocumentTokens returns the locations of the tokens in the document.
+// The tokens that not used by the document are skipped without looking at
+// their postings.
+func (idx *reader) LookupDocumentTokens(ctx context.Context, documentID int, tokens []int64) ([]index.TokenLocation, error) {
+	var locations []index.TokenLocation
+	docTokens := idx.docTokens[documentID]
+	for _, token := range tokens {
+		if _, found := slices.BinarySearch(docTokens, token); !found {
+			continue
+		}
+
+		for _, p := range idx.postings[token] {
+			if p.DocumentID != documentID {
+				continue
+			}
+
+			locations = append(locations, index.TokenLocation{
+				DocumentID: p.DocumentID,
+				Token:      token,
+				Start:      p.Start,
+				End:        p.End,
+			})
+		}
+	}
+
+	return locations, ctx.Err()
+}
+
+// FetchDocuments returns the documents with the specified IDs.
+func (idx *reader) FetchDocuments(ctx context.Context, ids []int) ([]index.Document, error) {
+	docs := make([]index.Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, exist := idx.documents[id]; exist {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, ctx.Err()
+}
+
+// DocumentLengths returns the number of runes in the text of documents.
+func (idx *reader) DocumentLengths(ctx context.Context, ids []int) (map[int]int, error) {
+	lengths := make(map[int]int, len(ids))
+	for _, id := range ids {
+		if doc, exist := idx.documents[id]; exist {
+			lengths[id] = utf8.RuneCountInString(doc.Arabic)
+		}
+	}
+
+	return lengths, ctx.Err()
+}
+
+// TokenFrequencies returns the number of documents that contain each token,
+// along with the number of all documents.
+func (idx *reader) TokenFrequencies(ctx context.Context, tokens []int64) (map[int64]int, int, error) {
+	frequencies := make(map[int64]int, len(tokens))
+	for _, token := range tokens {
+		if df := idx.frequencies[token]; df > 0 {
+			frequencies[token] = df
+		}
+	}
+
+	return frequencies, len(idx.documents), ctx.Err()
+}
+
+// DocumentPhonetics returns the phonetic of documents with the specified IDs.
+func (idx *reader) DocumentPhonetics(ctx context.Context, ids []int) (map[int]index.DocumentPhonetic, error) {
+	phonetics := make(map[int]index.DocumentPhonetic, len(ids))
+	for _, id := range ids {
+		ep, exist := idx.phonetics[id]
+		if !exist {
+			continue
+		}
+
+		dp, err := ep.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		phonetics[id] = dp
+	}
+
+	return phonetics, ctx.Err()
+}
+
+// ScanDocumentPhonetics calls fn with the phonetic of every document,
+// sorted by their IDs, until fn returns false or the context is cancelled.
+func (idx *reader) ScanDocumentPhonetics(ctx context.Context, fn func(id int, dp index.DocumentPhonetic) bool) error {
+	for _, id := range slices.Sorted(maps.Keys(idx.phonetics)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		dp, err := idx.phonetics[id].decode()
+		if err != nil {
+			return err
+		}
+
+		if !fn(id, dp) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// FindDocument returns the document with the specified identifier.
+func (idx *reader) FindDocument(ctx context.Context, identifier string) (index.Document, bool, error) {
+	documentID, exist := idx.identifiers[identifier]
+	if !exist {
+		return index.Document{}, false, ctx.Err()
+	}
+
+	return idx.documents[documentID], true, ctx.Err()
+}
+
+// SkeletonTokens reports whether the skeleton tokens of vocalized documents
+// are indexed. It never changes, so the lock is not needed.
+func (idx *Index) SkeletonTokens() bool {
+	return idx.skeletonTokens
+}
+
+// Close releases the documents and tokens that kept in memory.
+func (idx *Index) Close() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	clear(idx.documents)
+	clear(idx.identifiers)
+	clear(idx.docTokens)
+	clear(idx.postings)
+	clear(idx.frequencies)
+	clear(idx.phonetics)
+	return nil
+}
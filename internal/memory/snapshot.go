@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+// snapshotMagic is the header in the start of every snapshot file.
+const snapshotMagic = "LAFZI"
+
+// snapshotVersion is the version of snapshot format. It must be
+// increased whenever the format or the saved tokens are changed.
+const snapshotVersion = 1
+
+// maxSnapshotString is the max length of string in snapshot, used to
+// prevent allocating huge memory while reading a corrupted snapshot.
+const maxSnapshotString = 1 << 28
+
+// WriteSnapshot serializes the documents and their postings into w using a
+// versioned binary format. The snapshot can be loaded back using LoadSnapshot.
+//
+// The format starts with magic "LAFZI" and the format version, followed by 1 if
+// the skeleton tokens are indexed or 0 otherwise. Next is the last document
+// ID, then the documents which each saved as its ID, identifier,
+// Arabic text, its phonetic and skeleton that encoded using
+// index.EncodePhonetic, and 1 if it's unvocalized or 0 otherwise. Last is
+// the posting lists, which each saved as the token and the postings that
+// encoded using index.EncodePostings. All numbers are uvarints, while
+// strings and blobs are prefixed by their length.
+func (idx *Index) WriteSnapshot(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	sw := snapshotWriter{w: bufio.NewWriter(w)}
+	sw.writeRaw([]byte(snapshotMagic))
+	sw.writeUint(snapshotVersion)
+
+	sw.writeUint(boolUint(idx.skeletonTokens))
+	sw.writeUint(uint64(idx.lastID))
+
+	// Write documents, sorted by ID so the snapshot is deterministic
+	documentIDs := slices.Sorted(maps.Keys(idx.documents))
+	sw.writeUint(uint64(len(documentIDs)))
+	for _, id := range documentIDs {
+		doc := idx.documents[id]
+		sw.writeUint(uint64(doc.ID))
+		sw.writeBytes([]byte(doc.Identifier))
+		sw.writeBytes([]byte(doc.Arabic))
+		ep := idx.phonetics[id]
+		sw.writeBytes(ep.phonetic)
+		sw.writeBytes(ep.skeleton)
+		sw.writeUint(boolUint(ep.unvocalized))
+	}
+
+	// Write posting lists
+	tokens := slices.Sorted(maps.Keys(idx.postings))
+	sw.writeUint(uint64(len(tokens)))
+	for _, token := range tokens {
+		postings := index.SortPostings(slices.Clone(idx.postings[token]))
+		sw.writeUint(uint64(token))
+		sw.writeBytes(index.EncodePostings(postings))
+	}
+
+	if sw.err != nil {
+		return sw.err
+	}
+
+	return sw.w.Flush()
+}
+
+// LoadSnapshot creates a new in-memory index from the
+// snapshot that created by WriteSnapshot.
+func LoadSnapshot(r io.Reader) (*Index, error) {
+	sr := snapshotReader{r: bufio.NewReader(r)}
+
+	// Check the header
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr.r, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, fmt.Errorf("invalid snapshot: missing header")
+	}
+
+	version := sr.readUint()
+	if sr.err == nil && version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is not supported", version)
+	}
+
+	idx := New(sr.readUint() == 1)
+	idx.lastID = int(sr.readUint())
+
+	// Read documents
+	nDocument := sr.readUint()
+	for i := uint64(0); i < nDocument && sr.err == nil; i++ {
+		doc := index.Document{
+			ID:         int(sr.readUint()),
+			Identifier: string(sr.readBytes()),
+			Arabic:     string(sr.readBytes()),
+		}
+
+		idx.documents[doc.ID] = doc
+		idx.identifiers[doc.Identifier] = doc.ID
+		idx.phonetics[doc.ID] = encodedPhonetic{
+			phonetic:    sr.readBytes(),
+			skeleton:    sr.readBytes(),
+			unvocalized: sr.readUint() == 1,
+		}
+	}
+
+	// Read posting lists
+	nToken := sr.readUint()
+	for i := uint64(0); i < nToken && sr.err == nil; i++ {
+		token := int64(sr.readUint())
+		postings, err := index.DecodePostings(sr.readBytes())
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %v", err)
+		}
+
+		idx.postings[token] = postings
+		for _, p := range postings {
+			docTokens := idx.docTokens[p.DocumentID]
+			if len(docTokens) == 0 || docTokens[len(docTokens)-1] != token {
+				idx.docTokens[p.DocumentID] = append(docTokens, token)
+				idx.frequencies[token]++
+			}
+		}
+	}
+
+	if sr.err != nil {
+		if errors.Is(sr.err, io.EOF) {
+			sr.err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("invalid snapshot: %v", sr.err)
+	}
+
+	return idx, nil
+}
+
+// boolUint returns 1 for true and 0 for false.
+func boolUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type snapshotWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *snapshotWriter) writeUint(v uint64) {
+	if sw.err == nil {
+		n := binary.PutUvarint(sw.buf[:], v)
+		_, sw.err = sw.w.Write(sw.buf[:n])
+	}
+}
+
+func (sw *snapshotWriter) writeRaw(b []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(b)
+	}
+}
+
+func (sw *snapshotWriter) writeBytes(b []byte) {
+	sw.writeUint(uint64(len(b)))
+	sw.writeRaw(b)
+}
+
+type snapshotReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sr *snapshotReader) readUint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+
+	var v uint64
+	v, sr.err = binary.ReadUvarint(sr.r)
+	return v
+}
+
+func (sr *snapshotReader) readBytes() []byte {
+	n := sr.readUint()
+	if sr.err != nil {
+		return nil
+	}
+
+	if n > maxSnapshotString {
+		sr.err = fmt.Errorf("string too long")
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, sr.err = io.ReadFull(sr.r, b)
+	return b
+}
@@ -1,9 +1,19 @@
 package lafzi
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"unicode/utf16"
+	"unicode/utf8"
+
 	"github.com/hablullah/go-lafzi/internal/database"
-	"github.com/hablullah/go-lafzi/internal/phonetic"
-	"github.com/jmoiron/sqlx"
+	"github.com/hablullah/go-lafzi/internal/index"
+	"github.com/hablullah/go-lafzi/internal/memory"
+	"github.com/hablullah/go-lafzi/phonetic"
 	_ "modernc.org/sqlite"
 )
 
@@ -11,6 +21,21 @@ import (
 type Document struct {
 	Identifier string
 	Arabic     string
+
+	// Unvocalized marks the document whose Arabic text is written without
+	// harakat. Since its vowels are unknown, the document is indexed using
+	// its consonant skeleton, and the query is projected into its skeleton
+	// as well while searching.
+	Unvocalized bool
+
+	// Script is the script that used to write the Arabic text. By default
+	// it's written in Arabic script, however it can be written using
+	// Buckwalter transliteration as well. The text is converted into Arabic
+	// script before it's saved, so Result.Text is always in Arabic script.
+	// Since each Buckwalter character represents exactly one Arabic rune,
+	// the rune positions in the result are valid for both of them. Buckwalter
+	// only uses ASCII, so they can be used as byte and UTF-16 offsets too.
+	Script Script
 }
 
 // Result contains id of the suitable document and its confidence level.
@@ -18,75 +43,438 @@ type Result struct {
 	Identifier string
 	Text       string
 	Confidence float64
-	Positions  [][2]int
+
+	// Positions is the [start, end) rune index of the matching parts in
+	// Text, which is the same as Arabic text of the submitted document.
+	Positions [][2]int
+
+	// Locations is the same as Positions, measured in several units.
+	Locations []Location
+}
+
+// Location is the location of the matching part in the document text. Each of
+// them is [start, end) range in different unit, e.g. Byte is useful to slice
+// the text in Go, while UTF16 is useful to highlight the text in JavaScript.
+type Location struct {
+	Rune  [2]int
+	Byte  [2]int
+	UTF16 [2]int
+}
+
+// Page is a single page of search results, limited by the
+// limit and offset in the search options.
+type Page struct {
+	Results []Result
+
+	// Total is the number of all documents that match the
+	// query, regardless of the limit and offset.
+	Total int
+
+	// Capped is true if the query is too short to be split into n-grams,
+	// and there might be more matching documents than ShortQueryLimit.
+	// In that case only the best of them are kept, and Total is capped
+	// by the limit.
+	Capped bool
+}
+
+// SearchOptions is the options that used for a single search. Since it's
+// passed per call, each search may use its own options without affecting
+// the other searches that run concurrently.
+type SearchOptions struct {
+	// MinConfidence is the minimum confidence score for the search result.
+	// If it's zero or negative, the default 40% will be used.
+	MinConfidence float64
+
+	// Limit is the max number of results that will be returned.
+	// If it's zero or negative, all results will be returned.
+	Limit int
+
+	// Offset is the number of results that will be skipped
+	// before the results are returned.
+	Offset int
+
+	// CompletenessWeight and CompactnessWeight are the exponents that
+	// applied to the completeness and compactness score before they are
+	// multiplied into the confidence score. Bigger weight means the score is
+	// more important. If it's zero or negative, weight 1 will be used.
+	// They are ignored if Scorer is set.
+	CompletenessWeight float64
+	CompactnessWeight  float64
+
+	// Scorer is used to calculate the confidence score of each match. If
+	// it's nil, DefaultScorer with the weights above will be used.
+	Scorer Scorer
+
+	// Rerank is the number of top results that re-scored in the second pass,
+	// by aligning the query against the phonetic of the matched part in
+	// the document. The alignment counts the exact differences between them,
+	// so the confidence and positions are more precise than the ones from
+	// the matched trigrams. The re-scored results replace the scores from
+	// Scorer, and they are always placed before the other results. Since the
+	// alignment and Scorer don't use the same scale, the confidence is only
+	// comparable among the results that scored the same way, so a result
+	// after the re-scored ones might have a higher confidence. To make the
+	// whole page re-scored, use Rerank that not smaller than Offset + Limit.
+	// If it's zero or negative, the results are not re-scored.
+	Rerank int
+
+	// Confusions is the cost of substituting the phonetic runes that often
+	// confused by the users, which enables the fuzzy matching. The query
+	// trigrams are also matched with their confused runes, e.g. "hamd" is
+	// matched with "xamd", however the substituted trigrams only count as
+	// part of a match depending on the cost. The cost is used while aligning
+	// the query in Rerank as well. DefaultConfusionMatrix can be used here.
+	Confusions ConfusionMatrix
+
+	// ShortQueryLimit is the max number of documents that returned for the
+	// query that too short to be split into n-grams, e.g. "la" or "hu". Such
+	// query is matched by scanning the phonetic of every document instead of
+	// the indexed n-grams, and since it's found in most documents, only the
+	// best matches up to the limit are kept, which caps the Total in Page as
+	// well. The results are scored by how many runes matched exactly and how
+	// much of the document is covered by the query, so the shorter documents
+	// are preferred, while Scorer and Rerank are not used. The query with a
+	// single rune, e.g. "h", is too common so it doesn't match any document.
+	// If it's zero or negative, the default 100 will be used.
+	ShortQueryLimit int
+
+	// Script is the script that used to write the query. By default
+	// the script is detected from the query itself.
+	Script Script
+
+	// Schemes is the transliteration schemes that used to write the Latin
+	// query. If several schemes are used, the query is searched using each
+	// of them and the best result is used. If it's empty, IndonesianScheme
+	// will be used.
+	Schemes []*Scheme
+}
+
+// Scheme is the transliteration scheme, i.e. the convention that used
+// to write Arabic letters using Latin script.
+type Scheme = phonetic.Scheme
+
+// NewScheme creates a new transliteration scheme from a list of old, new
+// string pairs, where the new string uses the letters from the Indonesian
+// scheme, e.g. NewScheme("custom", "ee", "i", "oo", "u").
+func NewScheme(name string, oldnew ...string) *Scheme {
+	return phonetic.NewScheme(name, oldnew...)
+}
+
+// Built-in transliteration schemes.
+var (
+	IndonesianScheme = phonetic.Indonesian
+	JawiScheme       = phonetic.Jawi
+	EnglishScheme    = phonetic.English
+	TurkishScheme    = phonetic.Turkish
+	FrenchScheme     = phonetic.French
+	ISO233Scheme     = phonetic.ISO233
+	DIN31635Scheme   = phonetic.DIN31635
+)
+
+// Script is the script that used to write the search query or document.
+type Script int
+
+const (
+	// AutoScript detects the script from the query. If most of its
+	// letters are Arabic, ArabicScript is used, otherwise LatinScript.
+	AutoScript Script = iota
+
+	// LatinScript is for the query that written as Latin transliteration,
+	// e.g. "bismillahirrahmanirrahim".
+	LatinScript
+
+	// ArabicScript is for the query that written in Arabic script, either
+	// with or without harakat. If the query has no harakat, it's matched
+	// using the consonant skeleton of the documents.
+	ArabicScript
+
+	// BuckwalterScript is for the text that written using Buckwalter
+	// transliteration, e.g. "bisomi {ll~ahi". Unlike LatinScript, it's
+	// converted into Arabic script, so it's as exact as ArabicScript.
+	BuckwalterScript
+
+	// SafeBuckwalterScript is for the text that written using Safe
+	// Buckwalter transliteration, e.g. "bisomi Lll~ahi".
+	SafeBuckwalterScript
+)
+
+// toArabic converts the text that written in the specified script into
+// Arabic script. Returns false if the text can't be converted.
+func toArabic(s string, script Script) (string, bool) {
+	switch script {
+	case AutoScript, ArabicScript:
+		return s, true
+	case BuckwalterScript:
+		return phonetic.BuckwalterToArabic(s), true
+	case SafeBuckwalterScript:
+		return phonetic.SafeBuckwalterToArabic(s), true
+	default:
+		return s, false
+	}
+}
+
+var (
+	// ErrClosed is returned when the storage is used after it's closed.
+	ErrClosed = errors.New("lafzi: storage is closed")
+
+	// ErrNotFound is returned when the document doesn't exist in storage.
+	ErrNotFound = errors.New("lafzi: document is not found")
+)
+
+const (
+	defaultMinConfidence = 0.4
+	nGramSize            = 3
+	skeletonIdealGap     = 6
+)
+
+// Layout is the layout that used to store the reverse indexes.
+type Layout int
+
+const (
+	// DefaultLayout uses the layout of the existing storage,
+	// or RowLayout for the new storage.
+	DefaultLayout Layout = iota
+
+	// RowLayout stores each trigram occurence in a document as a
+	// separate row. It's easy to inspect using database editors.
+	RowLayout
+
+	// PostingLayout stores all occurences of a trigram as a compressed
+	// posting list, which split into small blocks so adding a document
+	// only updates the last block. It writes far fewer rows so the
+	// indexing is faster, and the search only reads a few blocks per
+	// trigram.
+	PostingLayout
+)
+
+// StorageOptions is the options that used while opening the storage.
+type StorageOptions struct {
+	// Layout is the layout for storing the reverse indexes. The layout is
+	// chosen when the storage is created, so if the existing storage uses
+	// a different layout, error will be returned.
+	Layout Layout
+
+	// DisableSkeletonTokens stops indexing the consonant skeleton of
+	// vocalized documents, which roughly halves the size of indexes.
+	// However, the vocalized documents can't be found anymore by Arabic
+	// query that written without harakat. Like layout, it's chosen when
+	// the storage is created, so the existing storage keeps its own setting.
+	DisableSkeletonTokens bool
 }
 
 // Storage is the container for storing reverse indexes for
-// Arabic documents that will be searched later. Use sqlite3
-// as database engine.
+// Arabic documents that will be searched later. By default it
+// uses sqlite3 as database engine, however it's also possible
+// to keep the indexes in memory using NewMemoryStorage.
 type Storage struct {
-	db            *sqlx.DB
-	minConfidence float64
+	idx index.Index
+
+	// minConfidence is the bits of the minimum confidence that used by
+	// SetMinConfidence, so it can be changed without waiting for any
+	// running operations.
+	minConfidence atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
 }
 
 // OpenStorage open the reverse indexes database in the specified path.
 func OpenStorage(path string) (*Storage, error) {
-	db, err := database.Open(path)
+	return OpenStorageContext(context.Background(), path)
+}
+
+// OpenStorageContext open the reverse indexes database in the specified
+// path, using the context to cancel the process if needed.
+func OpenStorageContext(ctx context.Context, path string) (*Storage, error) {
+	return OpenStorageWithOptions(ctx, path, StorageOptions{})
+}
+
+// OpenStorageWithOptions open the reverse indexes database in the specified
+// path using the specified options.
+func OpenStorageWithOptions(ctx context.Context, path string, opts StorageOptions) (*Storage, error) {
+	var layout database.Layout
+	switch opts.Layout {
+	case DefaultLayout:
+		layout = database.LayoutDefault
+	case RowLayout:
+		layout = database.LayoutRows
+	case PostingLayout:
+		layout = database.LayoutPostings
+	default:
+		return nil, fmt.Errorf("unknown layout %d", opts.Layout)
+	}
+
+	db, err := database.Open(ctx, path, layout, !opts.DisableSkeletonTokens)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Storage{db, 0.4}, nil
+	return newStorage(db), nil
+}
+
+// NewMemoryStorage returns a new empty storage that keeps the reverse
+// indexes in memory. It's useful for searching a fixed corpus that loaded
+// at startup, since it doesn't need any file and database engine.
+func NewMemoryStorage() *Storage {
+	return NewMemoryStorageWithOptions(StorageOptions{})
+}
+
+// NewMemoryStorageWithOptions returns a new empty memory storage using the
+// specified options. The layout is ignored, since it's only used by database.
+func NewMemoryStorageWithOptions(opts StorageOptions) *Storage {
+	return newStorage(memory.New(!opts.DisableSkeletonTokens))
+}
+
+func newStorage(idx index.Index) *Storage {
+	st := &Storage{idx: idx}
+	st.minConfidence.Store(math.Float64bits(defaultMinConfidence))
+	return st
+}
+
+// Close closes the storage and its underlying index. It waits until
+// all running operations are finished. Once closed, every method of the
+// storage will return ErrClosed. Calling Close more than once is safe.
+func (st *Storage) Close() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return nil
+	}
+
+	st.closed = true
+	return st.idx.Close()
+}
+
+// acquire marks the start of an operation, preventing the storage from
+// being closed until the operation is done. Once the operation finished,
+// st.mu.RUnlock must be called.
+func (st *Storage) acquire() error {
+	st.mu.RLock()
+	if st.closed {
+		st.mu.RUnlock()
+		return ErrClosed
+	}
+	return nil
 }
 
 // AddDocuments save and index the documents into the storage.
 func (st *Storage) AddDocuments(docs ...Document) error {
+	return st.AddDocumentsContext(context.Background(), docs...)
+}
+
+// AddDocumentsContext save and index the documents into the storage. If the
+// context is cancelled, none of the documents will be saved.
+func (st *Storage) AddDocumentsContext(ctx context.Context, docs ...Document) error {
+	// Make sure storage is still open
+	if err := st.acquire(); err != nil {
+		return err
+	}
+	defer st.mu.RUnlock()
+
 	// Convert Arabic text to phonetics
-	dbDocs := make([]database.InsertDocumentArg, len(docs))
+	args := make([]index.InsertDocumentArg, len(docs))
 	for i, doc := range docs {
-		dbDocs[i] = database.InsertDocumentArg{
-			Identifier: doc.Identifier,
-			Arabic:     doc.Arabic,
-			Phonetic:   phonetic.FromArabic(doc.Arabic),
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		arabic, ok := toArabic(doc.Arabic, doc.Script)
+		if !ok {
+			return fmt.Errorf("document %q: script %d is not supported for document",
+				doc.Identifier, doc.Script)
 		}
+
+		args[i] = index.NewInsertDocumentArg(doc.Identifier, arabic, doc.Unvocalized)
 	}
 
-	// Save documents to database
-	return database.InsertDocuments(st.db, dbDocs...)
+	// Save documents to index
+	return st.idx.InsertDocuments(ctx, args...)
 }
 
 // DeleteDocuments remove the documents in the storage.
 func (st *Storage) DeleteDocuments(identifiers ...string) error {
-	return database.DeleteDocuments(st.db, identifiers...)
+	return st.DeleteDocumentsContext(context.Background(), identifiers...)
+}
+
+// DeleteDocumentsContext remove the documents in the storage. If the
+// context is cancelled, none of the documents will be removed.
+func (st *Storage) DeleteDocumentsContext(ctx context.Context, identifiers ...string) error {
+	// Make sure storage is still open
+	if err := st.acquire(); err != nil {
+		return err
+	}
+	defer st.mu.RUnlock()
+
+	return st.idx.DeleteDocuments(ctx, identifiers...)
 }
 
 // SetMinConfidence set the minimum confidence score for
-// the search result. Default is 40%.
+// the search result that used by Search. Default is 40%.
+//
+// Deprecated: the minimum confidence is shared by every search that doesn't
+// specify its own options, so changing it affects the other searches as well.
+// Use SearchWithOptions instead.
 func (st *Storage) SetMinConfidence(f float64) {
-	switch {
-	case f > 1:
-		st.minConfidence = 1
-	case f <= 0:
-		st.minConfidence = 0.4 // default is 40%
-	default:
-		st.minConfidence = f
-	}
+	st.minConfidence.Store(math.Float64bits(normalizeMinConfidence(f)))
+}
+
+// defaultSearchOptions returns the search options that used by
+// the methods which don't accept any options, e.g. Search.
+func (st *Storage) defaultSearchOptions() SearchOptions {
+	minConfidence := math.Float64frombits(st.minConfidence.Load())
+	return SearchOptions{MinConfidence: minConfidence}
 }
 
 // Search for suitable documents using the specified query.
 func (st *Storage) Search(query string) ([]Result, error) {
-	// Normalize query
-	query = phonetic.NormalizeString(query)
+	return st.SearchContext(context.Background(), query)
+}
 
-	// Convert query to trigram tokens
-	tokens := phonetic.NGrams(query, 3)
+// SearchContext search for suitable documents using the specified query,
+// using the context to cancel the search if needed.
+func (st *Storage) SearchContext(ctx context.Context, query string) ([]Result, error) {
+	return st.SearchWithOptions(ctx, query, st.defaultSearchOptions())
+}
 
-	// Search tokens in database
-	searchResults, err := database.SearchTokens(st.db, st.minConfidence, tokens...)
+// SearchWithOptions search for suitable documents using the
+// specified query and options.
+func (st *Storage) SearchWithOptions(ctx context.Context, query string, opts SearchOptions) ([]Result, error) {
+	page, err := st.SearchPage(ctx, query, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	return page.Results, nil
+}
+
+// SearchPage search for suitable documents using the specified query and
+// options. Only documents within the limit and offset are fetched from the
+// storage, however the page also contains the total number of documents that
+// match the query, which is useful for pagination.
+func (st *Storage) SearchPage(ctx context.Context, query string, opts SearchOptions) (Page, error) {
+	// Make sure storage is still open
+	if err := st.acquire(); err != nil {
+		return Page{}, err
+	}
+	defer st.mu.RUnlock()
+
+	// Convert query to n-gram tokens
+	queries, indexOpts := prepareSearch(query, opts, st.idx.SkeletonTokens())
+
+	// Search tokens in index, using a single view so the whole search
+	// sees the same documents
+	var searchResults []index.SearchResult
+	var total int
+	var capped bool
+	err := st.idx.View(ctx, func(r index.Reader) (err error) {
+		searchResults, total, capped, err = index.SearchTokens(ctx, r, indexOpts, queries...)
+		return
+	})
+	if err != nil {
+		return Page{}, err
+	}
+
 	// Create final result
 	results := make([]Result, len(searchResults))
 	for i, sr := range searchResults {
@@ -96,8 +484,165 @@ func (st *Storage) Search(query string) ([]Result, error) {
 			Text:       sr.Text,
 			Confidence: searchResults[i].Confidence,
 			Positions:  searchResults[i].Positions,
+			Locations:  textLocations(sr.Text, sr.Positions),
+		}
+	}
+
+	return Page{
+		Results: results,
+		Total:   total,
+		Capped:  capped,
+	}, nil
+}
+
+// prepareSearch converts the query into n-gram tokens, and the search
+// options into the options that used by index. The skeleton tokens are
+// only searched if the index has them.
+func prepareSearch(query string, opts SearchOptions, skeletonTokens bool) ([]index.TokenQuery, index.SearchOptions) {
+	queries := queryTokens(query, opts, skeletonTokens)
+	indexOpts := index.SearchOptions{
+		MinConfidence:      normalizeMinConfidence(opts.MinConfidence),
+		Limit:              opts.Limit,
+		Offset:             opts.Offset,
+		CompletenessWeight: opts.CompletenessWeight,
+		CompactnessWeight:  opts.CompactnessWeight,
+		Rerank:             opts.Rerank,
+		Confusions:         index.ConfusionMatrix(opts.Confusions),
+		ShortQueryLimit:    opts.ShortQueryLimit,
+	}
+
+	if opts.Scorer != nil {
+		indexOpts.Scorer = func(m index.Match) float64 {
+			return opts.Scorer.Score(Match(m))
+		}
+	}
+
+	return queries, indexOpts
+}
+
+// queryTokens converts the query into the encoded n-gram tokens, according
+// to the script that used to write the query. If the query has vowels, its
+// skeleton is searched as well to find the unvocalized documents. The Arabic
+// query without harakat only has its skeleton, so it's only able to find
+// the vocalized documents if the skeleton tokens are enabled.
+func queryTokens(query string, opts SearchOptions, skeletonTokens bool) []index.TokenQuery {
+	script := opts.Script
+	if script == AutoScript {
+		script = LatinScript
+		if phonetic.IsArabic(query) {
+			script = ArabicScript
 		}
 	}
 
-	return results, nil
+	// Buckwalter is converted into Arabic, so it's searched as exact as Arabic
+	if script == BuckwalterScript || script == SafeBuckwalterScript {
+		query, _ = toArabic(query, script)
+		script = ArabicScript
+	}
+
+	switch {
+	case script != ArabicScript:
+		schemes := opts.Schemes
+		if len(schemes) == 0 {
+			schemes = []*Scheme{IndonesianScheme}
+		}
+
+		// Normalize query using each scheme, skipping the duplicate
+		var queries []index.TokenQuery
+		normalized := make(map[string]struct{})
+		for _, scheme := range schemes {
+			s := phonetic.NormalizeStringScheme(query, scheme)
+			if _, exist := normalized[s]; exist {
+				continue
+			}
+
+			normalized[s] = struct{}{}
+			queries = append(queries,
+				newTokenQuery(index.RegularToken, s, 0),
+				newTokenQuery(index.UnvocalizedToken, phonetic.SkeletonString(s), 0),
+				newTokenQuery(index.LooseUnvocalizedToken, phonetic.LooseSkeletonString(s), 0))
+		}
+		return queries
+
+	case phonetic.IsVocalized(query):
+		skeleton := phonetic.SkeletonFromArabic(query)
+		return []index.TokenQuery{
+			newTokenQuery(index.RegularToken, phonetic.FromArabic(query).String(), 0),
+			newTokenQuery(index.UnvocalizedToken, skeleton.String(), 0),
+			newTokenQuery(index.LooseUnvocalizedToken, phonetic.LooseSkeleton(skeleton).String(), 0),
+		}
+
+	default:
+		skeleton := phonetic.SkeletonFromArabic(query)
+		queries := []index.TokenQuery{
+			newTokenQuery(index.UnvocalizedToken, skeleton.String(), 0),
+			newTokenQuery(index.LooseUnvocalizedToken, phonetic.LooseSkeleton(skeleton).String(), 0),
+		}
+
+		// Skeleton tokens don't include the harakat, so in vocalized
+		// documents they are located farther apart.
+		if skeletonTokens {
+			queries = append(queries, newTokenQuery(index.SkeletonToken, skeleton.String(), skeletonIdealGap))
+		}
+		return queries
+	}
+}
+
+// newTokenQuery splits the phonetic string into n-grams,
+// then encode them as tokens with the specified kind.
+func newTokenQuery(kind index.TokenKind, s string, idealGap float64) index.TokenQuery {
+	ngrams := phonetic.NGrams(s, nGramSize)
+	tokens := make([]int64, len(ngrams))
+	for i, ngram := range ngrams {
+		tokens[i] = index.EncodeToken(kind, ngram)
+	}
+
+	return index.TokenQuery{
+		Tokens:   tokens,
+		Kind:     kind,
+		Text:     s,
+		IdealGap: idealGap,
+	}
+}
+
+// textLocations converts the rune positions in the text into locations.
+func textLocations(text string, positions [][2]int) []Location {
+	// Count the byte and UTF-16 offset for each rune index
+	nRune := utf8.RuneCountInString(text)
+	byteOffsets := make([]int, 0, nRune+1)
+	utf16Offsets := make([]int, 0, nRune+1)
+
+	var utf16Offset int
+	for i, r := range text {
+		byteOffsets = append(byteOffsets, i)
+		utf16Offsets = append(utf16Offsets, utf16Offset)
+		utf16Offset += utf16.RuneLen(r)
+	}
+	byteOffsets = append(byteOffsets, len(text))
+	utf16Offsets = append(utf16Offsets, utf16Offset)
+
+	// Convert the positions, clamped within the text
+	locations := make([]Location, len(positions))
+	for i, pos := range positions {
+		start := min(max(pos[0], 0), nRune)
+		end := min(max(pos[1], start), nRune)
+		locations[i] = Location{
+			Rune:  [2]int{start, end},
+			Byte:  [2]int{byteOffsets[start], byteOffsets[end]},
+			UTF16: [2]int{utf16Offsets[start], utf16Offsets[end]},
+		}
+	}
+
+	return locations
+}
+
+func normalizeMinConfidence(f float64) float64 {
+	switch {
+	case f > 1:
+		return 1
+	case f <= 0:
+		return defaultMinConfidence
+	default:
+		return f
+	}
 }
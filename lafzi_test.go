@@ -0,0 +1,323 @@
+package lafzi
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestSearchJawi(t *testing.T) {
+	st := NewMemoryStorage()
+	defer st.Close()
+
+	err := st.AddDocuments(
+		Document{Identifier: "surau", Arabic: "کامي ڤرݢي مڠاجي د سوراو", Unvocalized: true},
+		Document{Identifier: "lagu", Arabic: "اي سوک مڽاڽي لاݢو چينتا", Unvocalized: true},
+		Document{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"pergi mengaji", "surau"},
+		{"mengaji di surau", "surau"},
+		{"menyanyi lagu", "lagu"},
+		{"lagu cinta", "lagu"},
+		{"qul huwallahu ahad", "ikhlas"},
+	}
+
+	for _, tt := range tests {
+		results, err := st.SearchWithOptions(context.Background(), tt.query, SearchOptions{
+			Schemes: []*Scheme{IndonesianScheme, JawiScheme},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) == 0 || results[0].Identifier != tt.want {
+			t.Errorf("search %q: got %v, want %q on top", tt.query, identifiers(results), tt.want)
+		}
+	}
+}
+
+func identifiers(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Identifier
+	}
+	return ids
+}
+
+func TestSetMinConfidenceConcurrently(t *testing.T) {
+	st := NewMemoryStorage()
+	defer st.Close()
+
+	err := st.AddDocuments(Document{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Must be run with -race to detect the data race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			st.SetMinConfidence(0.4 + float64(i%5)/10)
+		}
+	}()
+
+	for range 100 {
+		if _, err := st.Search("qul huwallahu ahad"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+// testStorages opens the empty storage for each kind of index, so the
+// same test can be run against all of them.
+var testStorages = map[string]func(t *testing.T) *Storage{
+	"memory": func(t *testing.T) *Storage {
+		return NewMemoryStorage()
+	},
+	"sqlite-rows": func(t *testing.T) *Storage {
+		return openTestStorage(t, StorageOptions{Layout: RowLayout})
+	},
+	"sqlite-postings": func(t *testing.T) *Storage {
+		return openTestStorage(t, StorageOptions{Layout: PostingLayout})
+	},
+}
+
+func openTestStorage(t *testing.T, opts StorageOptions) *Storage {
+	path := filepath.Join(t.TempDir(), "lafzi.db")
+	st, err := OpenStorageWithOptions(context.Background(), path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSearchShortQueryLimit(t *testing.T) {
+	// The confused matches ("xu" for "hu") are added before the exact ones
+	docs := []Document{
+		{Identifier: "naudzu-1", Arabic: "نَعُوذُ"},
+		{Identifier: "naudzu-2", Arabic: "نَعُوذُ"},
+		{Identifier: "naudzu-3", Arabic: "نَعُوذُ"},
+		{Identifier: "huwa-1", Arabic: "هُوَ"},
+		{Identifier: "huwa-2", Arabic: "هُوَ"},
+		{Identifier: "huwa-3", Arabic: "هُوَ"},
+	}
+
+	tests := []struct {
+		limit      int
+		want       []string
+		wantCapped bool
+	}{
+		{2, []string{"huwa-1", "huwa-2"}, true},
+		{4, []string{"huwa-1", "huwa-2", "huwa-3", "naudzu-1"}, true},
+		{6, []string{"huwa-1", "huwa-2", "huwa-3", "naudzu-1", "naudzu-2", "naudzu-3"}, false},
+		{10, []string{"huwa-1", "huwa-2", "huwa-3", "naudzu-1", "naudzu-2", "naudzu-3"}, false},
+	}
+
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			if err := st.AddDocuments(docs...); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				page, err := st.SearchPage(context.Background(), "hu", SearchOptions{
+					Confusions:      DefaultConfusionMatrix,
+					ShortQueryLimit: tt.limit,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := identifiers(page.Results)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("limit %d: got %v, want %v", tt.limit, got, tt.want)
+				}
+
+				if page.Total != len(tt.want) || page.Capped != tt.wantCapped {
+					t.Errorf("limit %d: got total %d capped %v, want %d %v",
+						tt.limit, page.Total, page.Capped, len(tt.want), tt.wantCapped)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchShortQueryCoverage(t *testing.T) {
+	// The unvocalized documents only have the skeleton, so "hu" is
+	// searched there as "h", which is too short to match anything
+	docs := []Document{
+		{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"},
+		{Identifier: "huwa", Arabic: "هُوَ"},
+		{Identifier: "hadza", Arabic: "هذا", Unvocalized: true},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"h", nil},
+		{"hu", []string{"huwa", "ikhlas"}},
+	}
+
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			if err := st.AddDocuments(docs...); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				results, err := st.Search(tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := identifiers(results)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+				}
+
+				// Only the document that consists of the query is fully matched
+				for _, result := range results {
+					if result.Confidence >= 1 && result.Identifier != "huwa" {
+						t.Errorf("%q in %s: got confidence %v, want below 1",
+							tt.query, result.Identifier, result.Confidence)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchLigatures(t *testing.T) {
+	// The moon is outside the BMP, so it takes two UTF-16 units and the
+	// locations in each unit are different.
+	docs := []Document{
+		{Identifier: "lam-alef", Arabic: "ﻻَ إِلَٰهَ إِﻻَّ هُوَ"},
+		{Identifier: "allah", Arabic: "قُلْ هُوَ ﷲُ أَحَدٌ"},
+		{Identifier: "salawat", Arabic: "🌙 مُحَمَّدٌ ﷺ رَسُولُ اللَّهِ"},
+		{Identifier: "presentation", Arabic: "🌙 ﺑِﺴْﻢِ ﺍﻟﻠَّﻪِ ﺍﻟﺮَّﺣْﻤَٰﻦِ ﺍﻟﺮَّﺣِﻴﻢِ"},
+	}
+
+	tests := []struct {
+		query      string
+		identifier string
+		want       string
+		wantRune   [2]int
+		wantByte   [2]int
+		wantUTF16  [2]int
+	}{
+		{"illa huwa", "lam-alef", "ِﻻَّ هُوَ", [2]int{12, 21}, [2]int{23, 41}, [2]int{12, 21}},
+		{"huwallahu", "allah", "هُوَ ﷲُ", [2]int{5, 12}, [2]int{9, 23}, [2]int{5, 12}},
+		{"sallallahu alayhi wasallam", "salawat", "ﷺ", [2]int{12, 13}, [2]int{24, 27}, [2]int{13, 14}},
+		{"arrahman", "presentation", "ﺮَّﺣْﻤَٰﻦ", [2]int{19, 28}, [2]int{46, 68}, [2]int{20, 29}},
+	}
+
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			if err := st.AddDocuments(docs...); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				results, err := st.Search(tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(results) == 0 || results[0].Identifier != tt.identifier {
+					t.Errorf("search %q: got %v, want %q on top", tt.query, identifiers(results), tt.identifier)
+					continue
+				}
+
+				r := results[0]
+				if len(r.Positions) != 1 || len(r.Locations) != 1 {
+					t.Errorf("search %q: got positions %v, want a single match", tt.query, r.Positions)
+					continue
+				}
+
+				loc := r.Locations[0]
+				if r.Positions[0] != tt.wantRune || loc.Rune != tt.wantRune ||
+					loc.Byte != tt.wantByte || loc.UTF16 != tt.wantUTF16 {
+					t.Errorf("search %q: got position %v and location %+v, want rune %v byte %v utf16 %v",
+						tt.query, r.Positions[0], loc, tt.wantRune, tt.wantByte, tt.wantUTF16)
+				}
+
+				// Each unit must slice the same text
+				runes := []rune(r.Text)
+				units := utf16.Encode(runes)
+				got := []string{
+					string(runes[loc.Rune[0]:loc.Rune[1]]),
+					r.Text[loc.Byte[0]:loc.Byte[1]],
+					string(utf16.Decode(units[loc.UTF16[0]:loc.UTF16[1]])),
+				}
+
+				for _, text := range got {
+					if text != tt.want {
+						t.Errorf("search %q: got highlight %q, want %q", tt.query, text, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchArabicWithoutHarakat(t *testing.T) {
+	docs := []Document{
+		{Identifier: "ikhlas", Arabic: "قُلْ هُوَ اللَّهُ أَحَدٌ"},
+		{Identifier: "basmalah", Arabic: "بسم الله الرحمن الرحيم", Unvocalized: true},
+	}
+
+	// The vocalized document is found using its skeleton tokens,
+	// which are indexed by default.
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"قل هو الله احد", "ikhlas"},
+		{"بسم الله الرحمن", "basmalah"},
+		{"بِسْمِ اللَّهِ الرَّحْمَٰنِ", "basmalah"},
+	}
+
+	for name, open := range testStorages {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			defer st.Close()
+
+			if err := st.AddDocuments(docs...); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				results, err := st.Search(tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !slices.Contains(identifiers(results), tt.want) {
+					t.Errorf("search %q: got %v, want %q", tt.query, identifiers(results), tt.want)
+				}
+			}
+		})
+	}
+}
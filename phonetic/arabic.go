@@ -0,0 +1,452 @@
+package phonetic
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FromArabic convert Arabic string into its phonetic. The position in
+// phonetic refers to the rune in the original string.
+func FromArabic(s string) Group {
+	// If string empty, stop early
+	if s == "" {
+		return nil
+	}
+
+	// Normalize the converted phonetic
+	runes, origins := normalizeArabic(s)
+	return restorePositions(Normalize(convertArabic(runes)), origins)
+}
+
+// normalizeArabic normalizes the unicode of Arabic string using NFKC, which
+// is done before the string converted into phonetic. Since the normalized
+// string might have different length, e.g. ligature "ﻻ" is decomposed into
+// lam and alef, it also returns the position of each normalized rune in the
+// original string. The ligatures are also expanded into their vocalized form,
+// where every expanded rune is located at the ligature.
+func normalizeArabic(s string) (runes []rune, origins []int) {
+	var it norm.Iter
+	it.InitString(norm.NFKC, s)
+
+	var segmentStart int // rune index where the current segment started
+	for !it.Done() {
+		start := it.Pos()
+		normalized := []rune(string(it.Next()))
+		original := []rune(s[start:it.Pos()])
+
+		// Most of the time the normalized segment only reorders the runes in
+		// the original, so each of them has its own position. The rune that
+		// created by decomposition or composition uses the segment position.
+		used := make([]bool, len(original))
+		for _, r := range normalized {
+			origin := segmentStart
+			for i, or := range original {
+				if !used[i] && or == r {
+					used[i], origin = true, segmentStart+i
+					break
+				}
+			}
+
+			runes = append(runes, r)
+			origins = append(origins, origin)
+		}
+
+		segmentStart += len(original)
+	}
+
+	if strings.ContainsAny(s, ligatures) {
+		runes, origins = expandLigatures(runes, origins, []rune(s))
+	}
+
+	return
+}
+
+// ligatures is the ligatures that fixed by expandLigatures.
+const ligatures = string(allahLigature) + string(salawatLigature) +
+	string(lamAlefIsolated) + string(lamAlefFinal)
+
+// vocalizedLigatures is the vocalized form of the word ligatures. NFKC
+// decomposes them into letters without harakat, which makes e.g. "ﷲ" loses
+// its doubled lam and vowel.
+var vocalizedLigatures = map[rune]string{
+	allahLigature:   "اللَّه",
+	salawatLigature: "صَلَّى اللَّهُ عَلَيْهِ وَسَلَّمَ",
+}
+
+// expandLigatures fixes the runes that decomposed from ligatures by NFKC,
+// which can be recognized by their origin. The word ligatures are replaced by
+// their vocalized form, while the harakat that follow lam-alef ligature are
+// moved after the lam, since they belong to the lam instead of the alef.
+func expandLigatures(runes []rune, origins []int, original []rune) ([]rune, []int) {
+	expandedRunes := make([]rune, 0, len(runes))
+	expandedOrigins := make([]int, 0, len(origins))
+	for i := 0; i < len(runes); {
+		// Find the runes that decomposed from the same ligature
+		origin := origins[i]
+		j := i + 1
+		for j < len(runes) && origins[j] == origin {
+			j++
+		}
+
+		switch ligature := original[origin]; {
+		case vocalizedLigatures[ligature] != "":
+			for _, r := range vocalizedLigatures[ligature] {
+				expandedRunes = append(expandedRunes, r)
+				expandedOrigins = append(expandedOrigins, origin)
+			}
+
+		case (ligature == lamAlefIsolated || ligature == lamAlefFinal) && j-i == 2:
+			k := j
+			for k < len(runes) && unicode.Is(unicode.Mn, runes[k]) {
+				k++
+			}
+
+			expandedRunes = append(expandedRunes, lam)
+			expandedRunes = append(expandedRunes, runes[j:k]...)
+			expandedRunes = append(expandedRunes, alef)
+			expandedOrigins = append(expandedOrigins, origin)
+			expandedOrigins = append(expandedOrigins, origins[j:k]...)
+			expandedOrigins = append(expandedOrigins, origin)
+			j = k
+
+		default:
+			expandedRunes = append(expandedRunes, runes[i:j]...)
+			expandedOrigins = append(expandedOrigins, origins[i:j]...)
+		}
+
+		i = j
+	}
+
+	return expandedRunes, expandedOrigins
+}
+
+// restorePositions converts the position in phonetic from the rune index
+// in normalized string into the rune index in the original string.
+func restorePositions(group Group, origins []int) Group {
+	for i, d := range group {
+		if d.Pos >= 0 && d.Pos < len(origins) {
+			group[i].Pos = origins[d.Pos]
+		}
+	}
+	return group
+}
+
+// convertArabic convert each Arabic chars that already normalized by
+// normalizeArabic into its phonetic, without applying any heuristics.
+func convertArabic(runes []rune) Group {
+	// Convert Arabic chars into its phonetic
+	phonetics := make([]Data, 0, 2*len(runes)) // worst case, each rune is fathatain
+	lastLetter := -1                           // index where phonetic of the last letter started
+	var prevRune rune
+	for i, r := range runes {
+		// Some marks modify the phonetic of previous letter
+		switch r {
+		case tatweel:
+			continue
+
+		case shadda:
+			// Double the consonant, e.g. "rabbi"
+			phonetics = doubleLastConsonant(phonetics)
+			prevRune = r
+			continue
+
+		case smallHighRoundedZero, smallHighUprightRectangularZero:
+			// The previous letter is not pronounced
+			if lastLetter >= 0 {
+				phonetics = phonetics[:lastLetter]
+				lastLetter = -1
+			}
+			prevRune = r
+			continue
+
+		case superscriptAlef:
+			// In Uthmani script, waw or yeh that followed by superscript
+			// alef is pronounced as alef, e.g. "salah" and "hayah".
+			if n := len(phonetics); n > 0 && (prevRune == waw || prevRune == yeh || prevRune == farsiYeh || prevRune == alefMaksura) {
+				phonetics[n-1].Rune = 'a'
+				prevRune = r
+				continue
+			}
+		}
+
+		// Remember where the phonetic of letter is started
+		if isArabicLetter(r) {
+			lastLetter = len(phonetics)
+		}
+
+		replacementRunes := transformArabicRune(r, prevRune, phonetics)
+		for _, rr := range replacementRunes {
+			phonetics = append(phonetics, Data{
+				Rune: rr,
+				Pos:  i,
+			})
+		}
+
+		prevRune = r
+	}
+
+	return phonetics
+}
+
+// doubleLastConsonant duplicates the last consonant in phonetics. Since
+// shadda is put after harakat by unicode normalization, the consonant might
+// be followed by vowels, so the duplicate is inserted right after it.
+func doubleLastConsonant(phonetics []Data) []Data {
+	for i := len(phonetics) - 1; i >= 0; i-- {
+		switch phonetics[i].Rune {
+		case 'a', 'i', 'u', '0':
+			continue
+		default:
+			return slices.Insert(phonetics, i+1, phonetics[i])
+		}
+	}
+	return phonetics
+}
+
+func isArabicLetter(r rune) bool {
+	return unicode.Is(unicode.Arabic, r) && unicode.IsLetter(r)
+}
+
+func transformArabicRune(r rune, prevRune rune, phonetics []Data) []rune {
+	switch r {
+	case alefWithMaddaAbove:
+		// If it's preceded by fatha, it's just a long vowel.
+		// Otherwise, it's hamza followed by long vowel.
+		if n := len(phonetics); n > 0 && phonetics[n-1].Rune == 'a' {
+			return []rune{'a'}
+		}
+		return []rune{'x', 'a'}
+	case alefMaksura:
+		// If it's preceded by harakat, it's just a long vowel that
+		// already represented by the harakat, e.g. "hudan", "fi" and "ala".
+		// Otherwise it's pronounced as alef.
+		if isHarakat(prevRune) {
+			return nil
+		}
+		return []rune{'a'}
+	case jeem, thal, zain, zah, jeh:
+		return []rune{'z'}
+	case hah, khah, heh, hehGoal, hehDoachashmee, hehWithYehAbove:
+		return []rune{'h'}
+	case hamza, alef, ain, alefWasla,
+		alefWithHamzaAbove, alefWithHamzaBelow,
+		yehWithHamzaAbove, wawWithHamzaAbove,
+		hamzaAbove, hamzaBelow:
+		return []rune{'x'}
+	case theh, seen, sheen, sad:
+		return []rune{'s'}
+	case dal, dad, dul, ddal:
+		return []rune{'d'}
+	case tehMarbuta, teh, tah, tahWithThreeDotsAbove, tteh, tehMarbutaGoal:
+		return []rune{'t'}
+	case qaf, kaf, keheh:
+		return []rune{'k'}
+	case ghain, gaf, kafWithDotAbove, kafWithThreeDotsBelow, kehehWithDotAbove:
+		return []rune{'g'}
+	case feh, peh, veh, wawWithDotAbove:
+		return []rune{'f'}
+	case tcheh:
+		return []rune{'c'}
+	case ng:
+		return []rune{'q'}
+	case noonWithThreeDotsAbove, yehWithThreeDotsBelow:
+		return []rune{'v'}
+	case meem:
+		return []rune{'m'}
+	case noon, noonGhunna, smallHighNoon:
+		return []rune{'n'}
+	case lam:
+		return []rune{'l'}
+	case beh:
+		return []rune{'b'}
+	case yeh, farsiYeh, yehBarree, yehBarreeWithHamzaAbove, smallYeh, smallHighYeh:
+		return []rune{'y'}
+	case waw, smallWaw:
+		return []rune{'w'}
+	case reh, rreh:
+		return []rune{'r'}
+	case fathatan, openFathatan:
+		return []rune{'a', 'n'}
+	case dammatan, openDammatan:
+		return []rune{'u', 'n'}
+	case kasratan, openKasratan:
+		return []rune{'i', 'n'}
+	case fatha:
+		return []rune{'a'}
+	case damma:
+		return []rune{'u'}
+	case kasra:
+		return []rune{'i'}
+	case superscriptAlef:
+		return []rune{'a'}
+	case sukun, smallHighDotlessHeadOfKhah:
+		return []rune{'0'}
+	case smallHighMeemIsolated, smallLowMeem:
+		// Iqlab mark. After harakat it's the tanwin that pronounced as meem,
+		// e.g. "alimum bidzati", so it's converted into noon and left for the
+		// normalizer's iqlab rule. After noon, the noon is already there.
+		if prevRune == fatha || prevRune == damma || prevRune == kasra {
+			return []rune{'n'}
+		}
+		return nil
+	case smallHighLigatureSadLamAlefMaksura, smallHighLigatureQafLamAlefMaksura,
+		smallHighMeemInitial, smallHighLamAlef, smallHighJeem, smallHighThreeDots,
+		emptyCentreLowStop, emptyCentreHighStop, roundedHighStopWithFilledCentre:
+		// Waqf (pause) signs, which only tell the reader where to stop.
+		return nil
+	case endOfAyah, startOfRubElHizb, placeOfSajdah:
+		// Markers of ayah, hizb and sajdah, which are not pronounced.
+		return nil
+	case smallHighSeen, smallLowSeen:
+		// Sad that pronounced as seen (or vice versa), which
+		// already share the same phonetic.
+		return nil
+	case smallHighMadda:
+		// Long vowel, which is not distinguished from the short one.
+		return nil
+	default:
+		// Other marks, e.g. maddah above, don't change the phonetic.
+		return nil
+	}
+}
+
+const (
+	hamza              = '\u0621'
+	alefWithMaddaAbove = '\u0622'
+	alefWithHamzaAbove = '\u0623'
+	wawWithHamzaAbove  = '\u0624'
+	alefWithHamzaBelow = '\u0625'
+	yehWithHamzaAbove  = '\u0626'
+	alef               = '\u0627'
+	beh                = '\u0628'
+	tehMarbuta         = '\u0629'
+	teh                = '\u062A'
+	theh               = '\u062B'
+	jeem               = '\u062C'
+	hah                = '\u062D'
+	khah               = '\u062E'
+	dal                = '\u062F'
+	thal               = '\u0630'
+	reh                = '\u0631'
+	zain               = '\u0632'
+	seen               = '\u0633'
+	sheen              = '\u0634'
+	sad                = '\u0635'
+	dad                = '\u0636'
+	tah                = '\u0637'
+	zah                = '\u0638'
+	ain                = '\u0639'
+	ghain              = '\u063A'
+	tatweel            = '\u0640'
+	feh                = '\u0641'
+	qaf                = '\u0642'
+	kaf                = '\u0643'
+	lam                = '\u0644'
+	meem               = '\u0645'
+	noon               = '\u0646'
+	heh                = '\u0647'
+	waw                = '\u0648'
+	alefMaksura        = '\u0649'
+	yeh                = '\u064A'
+	fathatan           = '\u064B'
+	dammatan           = '\u064C'
+	kasratan           = '\u064D'
+	fatha              = '\u064E'
+	damma              = '\u064F'
+	kasra              = '\u0650'
+	shadda             = '\u0651'
+	sukun              = '\u0652'
+	hamzaAbove         = '\u0654'
+	hamzaBelow         = '\u0655'
+	superscriptAlef    = '\u0670'
+	alefWasla          = '\u0671'
+)
+
+// Ligatures that decomposed by NFKC.
+const (
+	allahLigature   = '\uFDF2'
+	salawatLigature = '\uFDFA'
+	lamAlefIsolated = '\uFEFB'
+	lamAlefFinal    = '\uFEFC'
+)
+
+// Quranic annotation marks, mostly used in Uthmani script.
+const (
+	smallHighLigatureSadLamAlefMaksura = '\u06D6'
+	smallHighLigatureQafLamAlefMaksura = '\u06D7'
+	smallHighMeemInitial               = '\u06D8'
+	smallHighLamAlef                   = '\u06D9'
+	smallHighJeem                      = '\u06DA'
+	smallHighThreeDots                 = '\u06DB'
+	smallHighSeen                      = '\u06DC'
+	endOfAyah                          = '\u06DD'
+	startOfRubElHizb                   = '\u06DE'
+	smallHighRoundedZero               = '\u06DF'
+	smallHighUprightRectangularZero    = '\u06E0'
+	smallHighDotlessHeadOfKhah         = '\u06E1'
+	smallHighMeemIsolated              = '\u06E2'
+	smallLowSeen                       = '\u06E3'
+	smallHighMadda                     = '\u06E4'
+	smallWaw                           = '\u06E5'
+	smallYeh                           = '\u06E6'
+	smallHighYeh                       = '\u06E7'
+	smallHighNoon                      = '\u06E8'
+	placeOfSajdah                      = '\u06E9'
+	emptyCentreLowStop                 = '\u06EA'
+	emptyCentreHighStop                = '\u06EB'
+	roundedHighStopWithFilledCentre    = '\u06EC'
+	smallLowMeem                       = '\u06ED'
+)
+
+// Open tanwin, which used in some Uthmani script for the tanwin
+// that followed by idgham or ikhfa.
+const (
+	openFathatan = '\u08F0'
+	openDammatan = '\u08F1'
+	openKasratan = '\u08F2'
+)
+
+// isHarakat returns true if the rune is harakat, i.e.
+// the tanwin, short vowels, shadda or sukun.
+func isHarakat(r rune) bool {
+	return (r >= fathatan && r <= sukun) || (r >= openFathatan && r <= openKasratan)
+}
+
+// Additional letters that used in Jawi (Malay) and Pegon (Javanese and
+// Sundanese) script.
+const (
+	tcheh                  = '\u0686'
+	dul                    = '\u068E'
+	tahWithThreeDotsAbove  = '\u069F'
+	ng                     = '\u06A0'
+	veh                    = '\u06A4'
+	kafWithDotAbove        = '\u06AC'
+	kafWithThreeDotsBelow  = '\u06AE'
+	noonWithThreeDotsAbove = '\u06BD'
+	wawWithDotAbove        = '\u06CF'
+	yehWithThreeDotsBelow  = '\u06D1'
+	kehehWithDotAbove      = '\u0762'
+)
+
+// Additional letters that used in Persian and Urdu script. Keheh and farsi yeh
+// are also commonly found in Arabic text that typed using Persian keyboard.
+const (
+	tteh                    = '\u0679'
+	peh                     = '\u067E'
+	ddal                    = '\u0688'
+	rreh                    = '\u0691'
+	jeh                     = '\u0698'
+	keheh                   = '\u06A9'
+	gaf                     = '\u06AF'
+	noonGhunna              = '\u06BA'
+	hehDoachashmee          = '\u06BE'
+	hehWithYehAbove         = '\u06C0'
+	hehGoal                 = '\u06C1'
+	tehMarbutaGoal          = '\u06C3'
+	farsiYeh                = '\u06CC'
+	yehBarree               = '\u06D2'
+	yehBarreeWithHamzaAbove = '\u06D3'
+)
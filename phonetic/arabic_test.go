@@ -0,0 +1,129 @@
+package phonetic
+
+import "testing"
+
+func TestFromArabicUthmani(t *testing.T) {
+	tests := []struct {
+		arabic string
+		want   string
+	}{
+		// Waw, yeh and alef maksura that followed by superscript alef
+		{"ٱلصَّلَوٰةَ", "salata"},
+		{"ٱلزَّكَوٰةَ", "zakata"},
+		{"عَلَىٰ", "ala"},
+		{"ٱلرَّحۡمَٰنِ", "rahmani"},
+
+		// Tanwin, including the open tanwin
+		{"هُدًى", "hudan"},
+		{"هُدࣰى", "hudan"},
+		{"هُدࣰى لِّلۡمُتَّقِينَ", "hudalilmutakina"},
+
+		// Sukun in the shape of small high dotless head of khah
+		{"بِسۡمِ ٱللَّهِ", "bismilahi"},
+		{"ٱلۡحَمۡدُ لِلَّهِ رَبِّ ٱلۡعَٰلَمِينَ", "alhamdulilahirabilxalamina"},
+
+		// Letters that not pronounced
+		{"قَالُوا۟", "kalu"},
+		{"أُو۟لَٰٓئِكَ", "ulaxika"},
+
+		// Small waw, yeh and noon
+		{"إِبۡرَٰهِـۧمَ", "ibrahima"},
+		{"تَأۡمَ۫نَّا", "tamana"},
+
+		// Iqlab, either after noon or tanwin
+		{"مِنۢ بَعۡدِ", "mimbadi"},
+		{"عَلِيمُۢ بِذَاتِ", "alimumbizati"},
+		{"عَلِيمࣱۢ بِذَاتِ", "alimumbizati"},
+		{"سَمِيعَۢا بَصِيرًا", "samixambasiranx"},
+
+		// Small high marks that don't change the phonetic, e.g. waqf
+		// signs, seen above sad, madda and markers of ayah and sajdah
+		{"لَا رَيۡبَۛ فِيهِۛ", "laraybafihi"},
+		{"وَيَبۡصُۜطُ", "wayabsutu"},
+		{"أُوْلَٰٓئِكَ", "ulaxika"},
+		{"وَٱسۡجُدۡۤ ۩", "waszud"},
+		{"ٱلدِّينِ ۝٤", "dini"},
+		{"۞ وَإِذۡ", "waxiz"},
+		{"قُلۡۖ", "kul"},
+		{"فَلَاۖ", "fala"},
+		{"قَالَۚ", "kala"},
+		{"يَعۡلَمُۗ", "yalamu"},
+		{"عَلِيمٌۗ", "alimun"},
+		{"يُؤۡمِنُونَۙ", "yuxminuna"},
+	}
+
+	for _, tt := range tests {
+		if got := FromArabic(tt.arabic).String(); got != tt.want {
+			t.Errorf("FromArabic(%q) = %q, want %q", tt.arabic, got, tt.want)
+		}
+	}
+}
+
+func TestSkeletonFromArabicJawi(t *testing.T) {
+	tests := []struct {
+		arabic string
+		want   string
+	}{
+		{"ڠاجي", "qz"},   // ngaji
+		{"مڠاجي", "mqz"}, // mengaji
+		{"ڽاڽي", "vv"},   // nyanyi
+		{"چاري", "cr"},   // cari
+		{"ڤرݢي", "frg"},  // pergi
+		{"ۏيديو", "fd"},  // video
+	}
+
+	for _, tt := range tests {
+		if got := SkeletonFromArabic(tt.arabic).String(); got != tt.want {
+			t.Errorf("SkeletonFromArabic(%q) = %q, want %q", tt.arabic, got, tt.want)
+		}
+	}
+}
+
+func TestFromArabicIgnoredMarks(t *testing.T) {
+	ignoredMarks := []rune{
+		smallHighLigatureSadLamAlefMaksura, smallHighLigatureQafLamAlefMaksura,
+		smallHighMeemInitial, smallHighLamAlef, smallHighJeem, smallHighThreeDots,
+		smallHighSeen, endOfAyah, startOfRubElHizb, smallLowSeen,
+		smallHighMadda, placeOfSajdah, emptyCentreLowStop,
+		emptyCentreHighStop, roundedHighStopWithFilledCentre,
+	}
+
+	want := FromArabic("قَالَ").String()
+	for _, mark := range ignoredMarks {
+		arabic := "قَالَ" + string(mark)
+		if got := FromArabic(arabic).String(); got != want {
+			t.Errorf("FromArabic(%q) with mark U+%04X = %q, want %q", arabic, mark, got, want)
+		}
+	}
+}
+
+func TestFromArabicLigatures(t *testing.T) {
+	tests := []struct {
+		arabic string
+		want   string
+	}{
+		// Lam-alef, where the harakat belong to the lam
+		{"ﻻَ إِلَٰهَ إِﻻَّ هُوَ", "لَا إِلَٰهَ إِلَّا هُوَ"},
+
+		// Word ligatures, which don't have any harakat
+		{"قُلْ هُوَ ﷲُ أَحَدٌ", "قُلْ هُوَ اللَّهُ أَحَدٌ"},
+		{"مُحَمَّدٌ ﷺ", "مُحَمَّدٌ صَلَّى اللَّهُ عَلَيْهِ وَسَلَّمَ"},
+
+		// Presentation forms
+		{"ﺑِﺴْﻢِ ﺍﻟﻠَّﻪِ ﺍﻟﺮَّﺣْﻤَٰﻦِ ﺍﻟﺮَّﺣِﻴﻢِ", "بِسْمِ اللَّهِ الرَّحْمَٰنِ الرَّحِيمِ"},
+	}
+
+	for _, tt := range tests {
+		want := FromArabic(tt.want).String()
+		if got := FromArabic(tt.arabic).String(); got != want {
+			t.Errorf("FromArabic(%q) = %q, want %q", tt.arabic, got, want)
+		}
+	}
+
+	// The expanded runes are located at the ligature
+	for _, data := range FromArabic("ﷺ") {
+		if data.Pos != 0 {
+			t.Errorf("FromArabic(%q): got %c at %d, want 0", "ﷺ", data.Rune, data.Pos)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package phonetic
+
+import "strings"
+
+// buckwalterRunes maps the Buckwalter transliteration into Arabic runes.
+// Beside the original table, it also includes the extended letters
+// that commonly used for Persian and Jawi texts.
+var buckwalterRunes = map[rune]rune{
+	'\'': hamza,
+	'|':  alefWithMaddaAbove,
+	'>':  alefWithHamzaAbove,
+	'&':  wawWithHamzaAbove,
+	'<':  alefWithHamzaBelow,
+	'}':  yehWithHamzaAbove,
+	'A':  alef,
+	'b':  beh,
+	'p':  tehMarbuta,
+	't':  teh,
+	'v':  theh,
+	'j':  jeem,
+	'H':  hah,
+	'x':  khah,
+	'd':  dal,
+	'*':  thal,
+	'r':  reh,
+	'z':  zain,
+	's':  seen,
+	'$':  sheen,
+	'S':  sad,
+	'D':  dad,
+	'T':  tah,
+	'Z':  zah,
+	'E':  ain,
+	'g':  ghain,
+	'_':  tatweel,
+	'f':  feh,
+	'q':  qaf,
+	'k':  kaf,
+	'l':  lam,
+	'm':  meem,
+	'n':  noon,
+	'h':  heh,
+	'w':  waw,
+	'Y':  alefMaksura,
+	'y':  yeh,
+	'F':  fathatan,
+	'N':  dammatan,
+	'K':  kasratan,
+	'a':  fatha,
+	'u':  damma,
+	'i':  kasra,
+	'~':  shadda,
+	'o':  sukun,
+	'`':  superscriptAlef,
+	'{':  alefWasla,
+	'P':  peh,
+	'J':  tcheh,
+	'V':  veh,
+	'G':  gaf,
+}
+
+// safeBuckwalterRunes maps the Safe Buckwalter transliteration into Arabic
+// runes. It's the same as Buckwalter, except the symbols are replaced by
+// letters so it can be used safely in XML, regex and file names.
+var safeBuckwalterRunes = map[rune]rune{
+	'C': hamza,
+	'M': alefWithMaddaAbove,
+	'O': alefWithHamzaAbove,
+	'W': wawWithHamzaAbove,
+	'I': alefWithHamzaBelow,
+	'Q': yehWithHamzaAbove,
+	'A': alef,
+	'b': beh,
+	'p': tehMarbuta,
+	't': teh,
+	'v': theh,
+	'j': jeem,
+	'H': hah,
+	'x': khah,
+	'd': dal,
+	'V': thal,
+	'r': reh,
+	'z': zain,
+	's': seen,
+	'c': sheen,
+	'S': sad,
+	'D': dad,
+	'T': tah,
+	'Z': zah,
+	'E': ain,
+	'g': ghain,
+	'_': tatweel,
+	'f': feh,
+	'q': qaf,
+	'k': kaf,
+	'l': lam,
+	'm': meem,
+	'n': noon,
+	'h': heh,
+	'w': waw,
+	'Y': alefMaksura,
+	'y': yeh,
+	'F': fathatan,
+	'N': dammatan,
+	'K': kasratan,
+	'a': fatha,
+	'u': damma,
+	'i': kasra,
+	'~': shadda,
+	'o': sukun,
+	'e': superscriptAlef,
+	'L': alefWasla,
+	'P': peh,
+	'J': tcheh,
+	'G': gaf,
+}
+
+// FromBuckwalter convert the Buckwalter transliteration into its phonetic.
+// Each Buckwalter character represents exactly one Arabic rune, so the
+// position in phonetic refers to the rune in the Buckwalter string.
+func FromBuckwalter(s string) Group {
+	return FromArabic(BuckwalterToArabic(s))
+}
+
+// FromSafeBuckwalter convert the Safe Buckwalter transliteration into its
+// phonetic. Like FromBuckwalter, the position in phonetic refers to the
+// rune in the Safe Buckwalter string.
+func FromSafeBuckwalter(s string) Group {
+	return FromArabic(SafeBuckwalterToArabic(s))
+}
+
+// BuckwalterToArabic converts the Buckwalter transliteration into Arabic
+// script. The unknown characters, e.g. space and punctuation, are kept
+// as it is, so the converted string has the same number of runes.
+func BuckwalterToArabic(s string) string {
+	return transliterate(s, buckwalterRunes)
+}
+
+// SafeBuckwalterToArabic converts the Safe Buckwalter transliteration
+// into Arabic script, keeping the unknown characters as it is.
+func SafeBuckwalterToArabic(s string) string {
+	return transliterate(s, safeBuckwalterRunes)
+}
+
+func transliterate(s string, table map[rune]rune) string {
+	return strings.Map(func(r rune) rune {
+		if ar, exist := table[r]; exist {
+			return ar
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,29 @@
+// Package phonetic converts Arabic text and its Latin transliteration into
+// the phonetic string that used by lafzi to index and search the documents.
+//
+// The phonetic string only uses the runes in Alphabet, where each rune
+// represents a group of Arabic letters that sound similar, e.g. 's' is used
+// for tha, sin, syin and shad, while 'x' is used for alif, hamza and ain. The
+// last three runes are used for ca, nga and nya in Jawi and Pegon script,
+// which only kept in the Latin query by the Jawi scheme. Since the phonetic
+// of Arabic text and its transliteration are written using the same runes,
+// they can be compared directly, as shown in the example of FromArabic.
+//
+// The phonetic of Arabic text is returned as Group, which keeps the position
+// of the source rune for each phonetic rune. It can be split into n-grams
+// using Group.Split that keep the position as well, which is useful to find
+// the location of a matching n-gram in the original text.
+//
+// The transliteration is normalized using Indonesian convention by default.
+// The other conventions can be used with NormalizeStringScheme.
+//
+// For text without harakat, the vowels are unknown so it's better to compare
+// their consonant skeleton using SkeletonFromArabic and SkeletonString.
+//
+// The exported types and functions are stable, and so is their output: the
+// same text always produces the same phonetic, and the runes in Alphabet
+// never change their order. Therefore the phonetic can be saved alongside
+// the original text, the same way lafzi saves it in its index. Any change
+// in the phonetic rules that alters the output for existing text is treated
+// as a breaking change.
+package phonetic
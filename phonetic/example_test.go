@@ -0,0 +1,73 @@
+package phonetic_test
+
+import (
+	"fmt"
+
+	"github.com/hablullah/go-lafzi/phonetic"
+)
+
+func ExampleFromArabic() {
+	// The phonetic of Arabic text and its Latin transliteration are
+	// written using the same runes, so they can be compared directly.
+	arabic := phonetic.FromArabic("بِسْمِ اللَّهِ")
+	latin := phonetic.NormalizeString("bismillah")
+	fmt.Println(arabic.String())
+	fmt.Println(latin)
+
+	// Each phonetic rune keeps the position of its source rune.
+	fmt.Println(arabic[0].Rune == 'b', arabic[0].Pos)
+	// Output:
+	// bismilahi
+	// bismilah
+	// true 0
+}
+
+func ExampleNormalize() {
+	// The group might come from any source, as long as
+	// it only uses the runes in phonetic.Alphabet.
+	var group phonetic.Group
+	for i, r := range "xalhamdu" {
+		group = append(group, phonetic.Data{Rune: r, Pos: i})
+	}
+
+	for _, d := range phonetic.Normalize(group) {
+		fmt.Printf("%c %d\n", d.Rune, d.Pos)
+	}
+	// Output:
+	// a 1
+	// l 2
+	// h 3
+	// a 4
+	// m 5
+	// d 6
+	// u 7
+}
+
+func ExampleNormalizeStringScheme() {
+	// In English scheme "th" is used for tha
+	fmt.Println(phonetic.FromArabic("ثُمَّ").String())
+	fmt.Println(phonetic.NormalizeStringScheme("thumma", phonetic.English))
+	// Output:
+	// suma
+	// suma
+}
+
+func ExampleNGrams() {
+	fmt.Println(phonetic.NGrams("bismilah", 3))
+	// Output:
+	// [bis ism smi mil ila lah]
+}
+
+func ExampleGroup_Split() {
+	// The n-grams keep the position of their source
+	// runes, so they can be located in the Arabic text.
+	for _, ngram := range phonetic.FromArabic("قُلْ هُوَ").Split(3) {
+		fmt.Println(ngram.Text, ngram.Start, ngram.End)
+	}
+	// Output:
+	// kul 0 3
+	// ulh 1 6
+	// lhu 2 7
+	// huw 5 8
+	// uwa 6 9
+}
@@ -4,19 +4,25 @@ import (
 	"strings"
 )
 
+// Data is a single phonetic rune, along with the position (rune index)
+// of its source in the original text.
 type Data struct {
 	Rune rune
 	Pos  int
 }
 
+// Group is the phonetic of a text.
 type Group []Data
 
+// NGram is the n-gram of phonetic group. Start and End are the range of
+// its source in the original text, where End is exclusive.
 type NGram struct {
 	Text  string
 	Start int
 	End   int
 }
 
+// String returns the phonetic string of the group.
 func (g Group) String() string {
 	var sb strings.Builder
 	for _, d := range g {
@@ -25,6 +31,8 @@ func (g Group) String() string {
 	return sb.String()
 }
 
+// Boundary returns the range of the group source in the original text,
+// where the end is exclusive. Returns -1, -1 if the group is empty.
 func (g Group) Boundary() (int, int) {
 	if len(g) == 0 {
 		return -1, -1
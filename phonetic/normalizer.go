@@ -28,54 +28,67 @@ var (
 
 	mnRemover = runes.Remove(runes.In(unicode.Mn))
 
-	similarSoundingRunesCleaner = runes.Map(func(r rune) rune {
-		switch r {
-		case 'o':
-			return 'a'
-		case 'e':
-			return 'i'
-		case 'v', 'p':
-			return 'f'
-		case 'q':
-			return 'k'
-		case 'j':
-			return 'z'
-		case '\'', '`',
-			'\u2019', // right single quotation mark
-			'\u02bc', // modifier letter apostrophe
-			'\u02bb', // modifier letter turned comma
-			'\u055a', // armenian apostrophe
-			'\ua78c', // latin small letter saltillo
-			'\u2032', // prime
-			'\u2035', // reversed prime
-			'\u02b9', // modifier letter prime
-			'\uff07', // fullwidth apostrophe
-			'\u2018': // left single quotation mark
-			return 'x'
-		default:
+	similarSoundingRunesCleaner = runes.Map(similarSoundingRune)
+
+	// In Jawi, 'q' and 'v' are used for nga and nya so they are kept.
+	jawiSimilarSoundingRunesCleaner = runes.Map(func(r rune) rune {
+		if isJawiRune(r) {
 			return r
 		}
+		return similarSoundingRune(r)
 	})
 
 	invalidPhoneticRunesCleaner = runes.Remove(
 		runes.Predicate(func(r rune) bool {
-			switch r {
-			case 'z', 'h', 'x', 's', 'd', 't', 'k', 'g',
-				'f', 'm', 'n', 'l', 'b', 'y', 'w', 'r',
-				'a', 'u', 'i', '0':
-				return false
-			default:
-				return true
-			}
+			return !isPhoneticRune(r) || isJawiRune(r)
+		}),
+	)
+
+	jawiInvalidPhoneticRunesCleaner = runes.Remove(
+		runes.Predicate(func(r rune) bool {
+			return !isPhoneticRune(r)
 		}),
 	)
 )
 
+// similarSoundingRune maps the Latin rune into the phonetic
+// rune that sounds similar, e.g. 'p' => 'f', 'e' => 'i'.
+func similarSoundingRune(r rune) rune {
+	switch r {
+	case 'o':
+		return 'a'
+	case 'e':
+		return 'i'
+	case 'v', 'p':
+		return 'f'
+	case 'q':
+		return 'k'
+	case 'j':
+		return 'z'
+	case '\'', '`',
+		'\u2019', // right single quotation mark
+		'\u02bc', // modifier letter apostrophe
+		'\u02bb', // modifier letter turned comma
+		'\u055a', // armenian apostrophe
+		'\ua78c', // latin small letter saltillo
+		'\u2032', // prime
+		'\u2035', // reversed prime
+		'\u02b9', // modifier letter prime
+		'\uff07', // fullwidth apostrophe
+		'\u2018': // left single quotation mark
+		return 'x'
+	default:
+		return r
+	}
+}
+
 // Normalize normalizes the phonetic group by using several heuristics.
 func Normalize(group Group) Group {
-	// Normalize the string
+	// Normalize the string. The phonetic of Jawi letters must be kept,
+	// so it's normalized as if it's written using Jawi scheme but
+	// without its rules, since the phonetic already uses its runes.
 	original := group.String()
-	normalized := NormalizeString(original)
+	normalized := NormalizeStringScheme(original, arabicScheme)
 
 	// Compare diffs between the original and normalized
 	edits := myers.Diff([]rune(original), []rune(normalized), 0, 0)
@@ -142,6 +155,19 @@ func Normalize(group Group) Group {
 
 // NormalizeString normalizes the phonetic string by using several heuristics.
 func NormalizeString(s string) string {
+	return NormalizeStringScheme(s, Indonesian)
+}
+
+// NormalizeStringScheme normalizes the phonetic string that written using the
+// specified transliteration scheme. The scheme rules are applied first, then
+// followed by the common heuristics.
+func NormalizeStringScheme(s string, scheme *Scheme) string {
+	// Apply the scheme rules. Since the rules might use letters with
+	// diacritics, it must be done before the marks are removed.
+	s = norm.NFC.String(s)
+	s = strings.ToLower(s)
+	s = scheme.replace(s)
+
 	// Normalize unicode
 	s = norm.NFKD.String(s)
 	s = mnRemover.String(s)
@@ -151,7 +177,11 @@ func NormalizeString(s string) string {
 	s = strings.ToLower(s)
 
 	// Normalize similar sounding runes, e.g. 'p' => 'f', 'e' => 'i'
-	s = similarSoundingRunesCleaner.String(s)
+	if scheme.isJawi() {
+		s = jawiSimilarSoundingRunesCleaner.String(s)
+	} else {
+		s = similarSoundingRunesCleaner.String(s)
+	}
 
 	// Mark possible hamzah location
 	s = rxHamzahA.ReplaceAllString(s, "ax$1")
@@ -162,7 +192,11 @@ func NormalizeString(s string) string {
 	s = normalizeSpaces(s)
 
 	// Remove invalid (or disallowed) phonetic runes
-	s = invalidPhoneticRunesCleaner.String(s)
+	if scheme.isJawi() {
+		s = jawiInvalidPhoneticRunesCleaner.String(s)
+	} else {
+		s = invalidPhoneticRunesCleaner.String(s)
+	}
 
 	// Normalize alif or hamzah 'x' in prefix
 	s = rxHamzahPrefix.ReplaceAllString(s, "${1}${4}${2}${3}${4}")
@@ -0,0 +1,61 @@
+package phonetic
+
+import "testing"
+
+func TestNormalizeString(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"bismillahirrahmanirrahim", "bismilahirahmanirahim"},
+		{"alhamdulillahi rabbil 'alamin", "alhamdulilahirabilxalamin"},
+		{"qul huwallahu ahad", "kulhuwalahuxahad"},
+		{"iyyaka na'budu wa iyyaka nasta'in", "iyakanabuduwaxiyakanastaxin"},
+		{"ihdinas shiratal mustaqim", "ihdinasiratalmustakim"},
+		{"innalillahi wa inna ilaihi raji'un", "inalilahiwaxinaxilaihirazixun"},
+		{"yaa ayyuhalladzina amanu", "yaxayuhalazinaxamanu"},
+		{"astaghfirullah", "astagfirulah"},
+
+		// Tajweed rules, i.e. ikhfa and idgham
+		{"mingkum", "minkum"},
+		{"man yaqulu", "mayakulu"},
+
+		// 'c' is not part of Arabic transliteration, so it's removed
+		{"chalid", "halid"},
+		{"chusnul khotimah", "husnulhatimah"},
+		{"cinta", "inta"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeString(tt.query); got != tt.want {
+			t.Errorf("NormalizeString(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeStringJawi(t *testing.T) {
+	tests := []struct {
+		query    string
+		want     string
+		skeleton string
+	}{
+		{"ngaji", "qazi", "qz"},
+		{"mengaji", "miqazi", "mqz"},
+		{"nyanyi", "vavi", "vv"},
+		{"cari", "cari", "cr"},
+		{"chari", "cari", "cr"},
+		{"pergi", "firgi", "frg"},
+		{"quran", "kuran", "krn"},
+	}
+
+	for _, tt := range tests {
+		got := NormalizeStringScheme(tt.query, Jawi)
+		if got != tt.want {
+			t.Errorf("NormalizeStringScheme(%q, Jawi) = %q, want %q", tt.query, got, tt.want)
+		}
+
+		if skeleton := SkeletonString(got); skeleton != tt.skeleton {
+			t.Errorf("SkeletonString(%q) = %q, want %q", got, skeleton, tt.skeleton)
+		}
+	}
+}
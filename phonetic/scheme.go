@@ -0,0 +1,139 @@
+package phonetic
+
+import "strings"
+
+// Scheme is the transliteration scheme, i.e. the convention that used to
+// write Arabic letters using Latin script. Each scheme has its own rules to
+// replace the letters into the phonetic, which applied before the common
+// heuristics in NormalizeString.
+type Scheme struct {
+	name     string
+	replacer *strings.Replacer
+
+	// jawi marks the scheme whose phonetic uses the runes for letters in
+	// Jawi and Pegon script, which are removed by the other schemes.
+	jawi bool
+}
+
+// NewScheme creates a new transliteration scheme from a list of old, new
+// string pairs. The old strings are compared in lowercase in the order
+// they appear, without overlapping matches. It panics if given an odd
+// number of arguments.
+func NewScheme(name string, oldnew ...string) *Scheme {
+	return &Scheme{
+		name:     name,
+		replacer: strings.NewReplacer(oldnew...),
+	}
+}
+
+// Name returns the name of the scheme.
+func (s *Scheme) Name() string {
+	return s.name
+}
+
+// isJawi returns true if the scheme keeps the runes for Jawi letters.
+func (s *Scheme) isJawi() bool {
+	return s != nil && s.jawi
+}
+
+// replace applies the scheme rules into the lowercase string.
+func (s *Scheme) replace(str string) string {
+	if s == nil || s.replacer == nil {
+		return str
+	}
+	return s.replacer.Replace(str)
+}
+
+// Built-in transliteration schemes.
+var (
+	// Indonesian is the scheme that commonly used in Indonesia and Malaysia,
+	// e.g. "sy" for syin and "ts" for tsa. Its rules are already handled by
+	// the common heuristics, so it's the default scheme.
+	Indonesian = NewScheme("indonesian")
+
+	// Jawi is the scheme for Malay and Javanese words that written in Latin,
+	// which used to search the texts written in Jawi or Pegon script, e.g.
+	// "ng" for nga, "ny" for nya and "c" for ca. In the other schemes these
+	// letters are treated as Arabic transliteration, e.g. "ng" as ikhfa, so
+	// this scheme must be used to find the Jawi and Pegon letters.
+	Jawi = &Scheme{
+		name: "jawi",
+		replacer: strings.NewReplacer(
+			"ng", "q",
+			"ny", "v",
+			"ch", "c",
+			"q", "k",
+			"v", "f",
+		),
+		jawi: true,
+	}
+
+	// arabicScheme is used to normalize the phonetic of Arabic text,
+	// which might contain the runes for Jawi letters.
+	arabicScheme = &Scheme{name: "arabic", jawi: true}
+
+	// English is the scheme that commonly used in English texts,
+	// e.g. "th" for tha, "dh" for dad and "ee" for long i.
+	English = NewScheme("english",
+		"th", "s",
+		"dh", "d",
+		"ee", "i",
+		"oo", "u",
+		"ou", "u",
+	)
+
+	// Turkish is the scheme that used in Turkish, e.g. "c" and "ç" for jim,
+	// "ş" for syin and "v" for waw.
+	Turkish = NewScheme("turkish",
+		"ç", "j",
+		"c", "z",
+		"ş", "s",
+		"ğ", "g",
+		"ı", "i",
+		"ö", "u",
+		"v", "w",
+	)
+
+	// French is the scheme that used in French, e.g. "ch" for syin,
+	// "dj" for jim and "ou" for waw.
+	French = NewScheme("french",
+		"ch", "s",
+		"dj", "z",
+		"ou", "u",
+	)
+
+	// ISO233 is the ISO 233 transliteration of Arabic characters.
+	ISO233 = NewScheme("iso233",
+		"ṯ", "s",
+		"ǧ", "z",
+		"ḥ", "h",
+		"ẖ", "h",
+		"ḏ", "z",
+		"š", "s",
+		"ṣ", "s",
+		"ḍ", "d",
+		"ṭ", "t",
+		"ẓ", "z",
+		"ġ", "g",
+		"ẗ", "t",
+		"ʿ", "x",
+		"ʾ", "x",
+	)
+
+	// DIN31635 is the DIN 31635 transliteration of Arabic alphabet.
+	DIN31635 = NewScheme("din31635",
+		"ṯ", "s",
+		"ǧ", "z",
+		"ḥ", "h",
+		"ḫ", "h",
+		"ḏ", "z",
+		"š", "s",
+		"ṣ", "s",
+		"ḍ", "d",
+		"ṭ", "t",
+		"ẓ", "z",
+		"ġ", "g",
+		"ʿ", "x",
+		"ʾ", "x",
+	)
+)
@@ -0,0 +1,45 @@
+package phonetic
+
+import "testing"
+
+func TestSchemes(t *testing.T) {
+	// The query written using each scheme must have the
+	// same phonetic as the Arabic word that it transliterates.
+	tests := []struct {
+		scheme *Scheme
+		query  string
+		arabic string
+	}{
+		{Indonesian, "adzan", "أَذَان"},
+		{Indonesian, "'arsy", "عَرْش"},
+		{Indonesian, "akhirat", "آخِرَة"},
+		{English, "thumma", "ثُمَّ"},
+		{English, "dhalla", "ضَلَّ"},
+		{English, "kabeer", "كَبِير"},
+		{English, "nour", "نُور"},
+		{Turkish, "cannat", "جَنَّة"},
+		{Turkish, "şükür", "شُكُور"},
+		{Turkish, "vahid", "وَاحِد"},
+		{Turkish, "çannat", "جَنَّة"},
+		{French, "chahid", "شَهِيد"},
+		{French, "djannat", "جَنَّة"},
+		{French, "nour", "نُور"},
+		{ISO233, "ṣalāt", "صَلَاة"},
+		{ISO233, "ǧannat", "جَنَّة"},
+		{ISO233, "ḏālika", "ذَٰلِكَ"},
+		{DIN31635, "ḫalīfat", "خَلِيفَة"},
+		{DIN31635, "šukr", "شُكْر"},
+		{DIN31635, "ḍalla", "ضَلَّ"},
+		{Jawi, "ngaji", "ڠَاجِي"},
+		{Jawi, "nyanyi", "ڽَاڽِي"},
+	}
+
+	for _, tt := range tests {
+		got := NormalizeStringScheme(tt.query, tt.scheme)
+		want := FromArabic(tt.arabic).String()
+		if got != want {
+			t.Errorf("NormalizeStringScheme(%q, %s) = %q, want %q",
+				tt.query, tt.scheme.Name(), got, want)
+		}
+	}
+}
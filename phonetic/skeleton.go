@@ -0,0 +1,150 @@
+package phonetic
+
+import "unicode"
+
+// Skeleton returns the consonant skeleton of the phonetic group, i.e. the
+// group without vowels. Alif, waw and yeh are removed as well since in text
+// without harakat they are mostly used as long vowels, so the skeleton of
+// vocalised and unvocalised text will be similar.
+func Skeleton(group Group) Group {
+	return filterSkeleton(group, isSkeletonVowel, false)
+}
+
+// LooseSkeleton returns the consonant skeleton without noon. In Latin query
+// noon is often added (tanwin) or removed (tajweed rules) from its Arabic
+// spelling, so it's skipped to make the skeleton more tolerant. Once noon
+// is removed, the identic adjacent consonants are merged as well, e.g.
+// "mim ba'di" and "min ba'di" (iqlab) are both become "mbd".
+func LooseSkeleton(group Group) Group {
+	return filterSkeleton(group, isLooseSkeletonVowel, true)
+}
+
+// SkeletonString returns the consonant skeleton of the phonetic string.
+func SkeletonString(s string) string {
+	return Skeleton(groupFromString(s)).String()
+}
+
+// LooseSkeletonString returns the loose consonant skeleton of the phonetic string.
+func LooseSkeletonString(s string) string {
+	return LooseSkeleton(groupFromString(s)).String()
+}
+
+func filterSkeleton(group Group, skip func(rune) bool, merge bool) Group {
+	skeleton := make(Group, 0, len(group))
+	for _, d := range group {
+		// Skip the vowels
+		if skip(d.Rune) {
+			continue
+		}
+
+		// Merge identic adjacent consonant if needed
+		if n := len(skeleton); merge && n > 0 && skeleton[n-1].Rune == d.Rune {
+			continue
+		}
+
+		skeleton = append(skeleton, d)
+	}
+	return skeleton
+}
+
+func groupFromString(s string) Group {
+	var group Group
+	for i, r := range []rune(s) {
+		group = append(group, Data{Rune: r, Pos: i})
+	}
+	return group
+}
+
+func isSkeletonVowel(r rune) bool {
+	switch r {
+	case 'a', 'i', 'u', 'x', 'w', 'y', '0':
+		return true
+	default:
+		return false
+	}
+}
+
+func isLooseSkeletonVowel(r rune) bool {
+	return r == 'n' || isSkeletonVowel(r)
+}
+
+// IsArabic returns true if the string is mostly written in Arabic script.
+func IsArabic(s string) bool {
+	var nArabic, nOther int
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r):
+			continue
+		case unicode.Is(unicode.Arabic, r):
+			nArabic++
+		default:
+			nOther++
+		}
+	}
+	return nArabic > nOther
+}
+
+// IsVocalized returns true if most letters in the Arabic string are
+// accompanied by harakat, so its vowels are known.
+func IsVocalized(s string) bool {
+	var nLetter, nVowel int
+	for _, r := range s {
+		switch {
+		case r >= fathatan && r <= kasra, r == superscriptAlef,
+			r >= openFathatan && r <= openKasratan:
+			nVowel++
+		case isArabicLetter(r):
+			nLetter++
+		}
+	}
+	return nLetter > 0 && 2*nVowel >= nLetter
+}
+
+// SkeletonFromArabic convert the Arabic string into its consonant skeleton.
+// The harakat are ignored, so the skeleton is the same whether the string is
+// written with or without harakat. Since the vowels are unknown, the tajweed
+// rules in Normalize can't be applied, so only the silent lam in the definite
+// article (alif lam syamsiah) is removed.
+func SkeletonFromArabic(s string) Group {
+	// If string empty, stop early
+	if s == "" {
+		return nil
+	}
+
+	// Remove the phonetic that comes from harakat and shadda
+	runes, origins := normalizeArabic(s)
+	group := convertArabic(runes)
+	letters := make(Group, 0, len(group))
+	for _, d := range group {
+		if unicode.Is(unicode.Mn, runes[d.Pos]) {
+			continue
+		}
+
+		if n := len(letters); n > 0 && letters[n-1] == d {
+			continue
+		}
+
+		letters = append(letters, d)
+	}
+
+	// Remove the silent lam, e.g. "xlrhmn" => "xrhmn"
+	cleaned := make(Group, 0, len(letters))
+	for i, d := range letters {
+		isSilentLam := d.Rune == 'l' && i > 0 && i < len(letters)-1 &&
+			letters[i-1].Rune == 'x' && isSunLetter(letters[i+1].Rune)
+		if !isSilentLam {
+			cleaned = append(cleaned, d)
+		}
+	}
+
+	return restorePositions(Skeleton(cleaned), origins)
+}
+
+func isSunLetter(r rune) bool {
+	switch r {
+	case 'z', 's', 'd', 't', 'n', 'l', 'r':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package phonetic
+
+import "strings"
+
+// NGrams splits a string into n-grams of specified size
+func NGrams(s string, n int) []string {
+	// Make sure n is not zero
+	if n <= 0 {
+		return nil
+	}
+
+	// Make sure string is longer than n
+	runes := []rune(s)
+	if len(runes) < n {
+		return nil
+	}
+
+	// Pre-allocate slice with exact capacity needed
+	numNGrams := len(runes) - n + 1
+	ngrams := make([]string, 0, numNGrams)
+
+	for i := 0; i <= len(runes)-n; i++ {
+		ngram := string(runes[i : i+n])
+		ngrams = append(ngrams, ngram)
+	}
+
+	return ngrams
+}
+
+// Alphabet is the runes that used in phonetic string. New runes are always
+// appended at the end, so the index of each rune in Alphabet is stable and
+// can be used to encode the phonetic, e.g. as the key of custom index.
+const Alphabet = "zhxsdtkgfmnlbywraui0cqv"
+
+// jawiRunes is the runes in Alphabet that only used by the letters in Jawi
+// and Pegon script, i.e. 'c' for ca, 'q' for nga and 'v' for nya. They are
+// removed from the Latin query, unless it's written using the Jawi scheme.
+const jawiRunes = "cqv"
+
+// isJawiRune returns true if the rune is one of jawiRunes.
+func isJawiRune(r rune) bool {
+	return strings.ContainsRune(jawiRunes, r)
+}
+
+// isPhoneticRune returns true if the rune is part of Alphabet.
+func isPhoneticRune(r rune) bool {
+	return r < 128 && phoneticRunes[r]
+}
+
+// phoneticRunes marks the ASCII runes that part of Alphabet.
+var phoneticRunes = func() [128]bool {
+	var runes [128]bool
+	for _, r := range Alphabet {
+		runes[r] = true
+	}
+	return runes
+}()
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/hablullah/go-lafzi"
+)
+
+// broadQueries are queries that match a lot of ayah, which
+// used to measure how fast the documents are fetched.
+var broadQueries = []string{"allah", "rabbi", "inna", "qala", "min"}
+
+// BenchmarkSearchBroad measures the search for queries that match a lot of
+// ayah, where most of the time is spent to fetch the matching documents.
+// The Quran is indexed once before the benchmark is started, and the
+// result of different commits can be compared using benchstat.
+func BenchmarkSearchBroad(b *testing.B) {
+	storage, err := lafzi.OpenStorage(filepath.Join(b.TempDir(), "quran.lafzi"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer storage.Close()
+
+	if err = prepareStorage(storage, false); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, limit := range []int{0, 10} {
+		for _, query := range broadQueries {
+			b.Run(fmt.Sprintf("limit=%d/%s", limit, query), func(b *testing.B) {
+				var total int
+				for b.Loop() {
+					page, err := storage.SearchPage(ctx, query, lafzi.SearchOptions{Limit: limit})
+					if err != nil {
+						b.Fatal(err)
+					}
+					total = page.Total
+				}
+				b.ReportMetric(float64(total), "results")
+			})
+		}
+	}
+}
@@ -17,9 +17,10 @@ func main() {
 	os.RemoveAll("quran.lafzi")
 	storage, err := lafzi.OpenStorage("quran.lafzi")
 	checkError(err)
+	defer storage.Close()
 
 	// Prepare storage
-	err = prepareStorage(storage)
+	err = prepareStorage(storage, false)
 	checkError(err)
 	goto bench
 
@@ -27,9 +28,12 @@ func main() {
 bench:
 	err = runBenchmark(storage)
 	checkError(err)
+
+	err = runUnvocalizedBenchmark()
+	checkError(err)
 }
 
-func prepareStorage(st *lafzi.Storage) error {
+func prepareStorage(st *lafzi.Storage, unvocalized bool) error {
 	start := time.Now()
 	fmt.Println("START INDEXING")
 
@@ -43,9 +47,14 @@ func prepareStorage(st *lafzi.Storage) error {
 			identifier = fmt.Sprintf("%d:%d", surah.ID, ayah)
 		}
 
+		if unvocalized {
+			ayah = removeHarakat(ayah)
+		}
+
 		docs[i] = lafzi.Document{
-			Identifier: identifier,
-			Arabic:     ayah,
+			Identifier:  identifier,
+			Arabic:      ayah,
+			Unvocalized: unvocalized,
 		}
 	}
 
@@ -66,7 +75,7 @@ func runBenchmark(st *lafzi.Storage) error {
 	var nTruePos, nFalseNeg int
 	start := time.Now()
 
-	runScenario := func(sc Scenario) error {
+	runScenario := func(ctx context.Context, sc Scenario) error {
 		start := time.Now()
 
 		// Prepare variables to score this scenario
@@ -77,7 +86,7 @@ func runBenchmark(st *lafzi.Storage) error {
 		// Process each query
 		for _, query := range sc.Queries {
 			// Search this query
-			results, err := st.Search(query)
+			results, err := st.SearchContext(ctx, query)
 			if err != nil {
 				return err
 			}
@@ -118,14 +127,14 @@ func runBenchmark(st *lafzi.Storage) error {
 	}
 
 	var wg sync.WaitGroup
-	g, _ := errgroup.WithContext(context.Background())
+	g, ctx := errgroup.WithContext(context.Background())
 	g.SetLimit(runtime.GOMAXPROCS(0))
 
 	wg.Add(len(scenarios))
 	for _, sc := range scenarios {
 		g.Go(func() error {
 			defer wg.Done()
-			return runScenario(sc)
+			return runScenario(ctx, sc)
 		})
 	}
 
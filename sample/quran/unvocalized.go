@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/hablullah/go-lafzi"
+)
+
+// runUnvocalizedBenchmark runs the benchmark against the Quran that written
+// without harakat, to measure the recall of the unvocalized documents.
+func runUnvocalizedBenchmark() error {
+	fmt.Println("UNVOCALIZED QURAN")
+
+	storage := lafzi.NewMemoryStorage()
+	defer storage.Close()
+
+	err := prepareStorage(storage, true)
+	if err != nil {
+		return err
+	}
+
+	return runBenchmark(storage)
+}
+
+// removeHarakat removes the harakat and other Quranic marks from the text.
+func removeHarakat(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -23,6 +23,7 @@ func main() {
 	os.RemoveAll("sample.lafzi")
 	storage, err := lafzi.OpenStorage("sample.lafzi")
 	checkError(err)
+	defer storage.Close()
 
 	// Prepare documents
 	var docs []lafzi.Document
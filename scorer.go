@@ -0,0 +1,162 @@
+package lafzi
+
+import (
+	"math"
+
+	"github.com/hablullah/go-lafzi/internal/index"
+)
+
+// Match is the candidate match of the query in a document. The query is split
+// into trigrams, and the match is the group of consecutive trigrams that found
+// in the document.
+type Match struct {
+	// Tokens is the index of the matched trigrams in the query, while Costs
+	// is the substitution cost of each trigram if it's matched using the
+	// confused runes in SearchOptions.Confusions. Costs is nil if all
+	// trigrams are matched exactly.
+	Tokens []int
+	Costs  []float64
+
+	// Positions is the rune positions of the matched trigrams in the document
+	// text, while Start and End are the range of the whole match.
+	Positions []int
+	Start     int
+	End       int
+
+	// QueryLength is the number of trigrams in the query, while IdealGap is
+	// the ideal gap between the matched positions. The ideal gap depends on
+	// the kind of query, e.g. the skeleton query has a wider gap since the
+	// harakat are skipped.
+	QueryLength int
+	IdealGap    float64
+
+	// IDF is the inverse document frequency of each trigram in the query,
+	// which is higher for the trigram that used by fewer documents.
+	IDF []float64
+
+	// DocumentLength is the number of runes in the document text.
+	DocumentLength int
+}
+
+// Scorer calculates the confidence score of the match, which should be
+// between 0 and 1. The match whose score is below the minimum confidence is
+// ignored, and the best score of a document is used as its confidence.
+type Scorer interface {
+	Score(m Match) float64
+}
+
+// DefaultScorer is the default scorer, which multiplies the completeness
+// (how many trigrams are matched) and the compactness (how close the matched
+// trigrams to each other) of the match.
+type DefaultScorer struct {
+	// CompletenessWeight and CompactnessWeight are the exponents that
+	// applied to the completeness and compactness score. If it's zero or
+	// negative, weight 1 will be used.
+	CompletenessWeight float64
+	CompactnessWeight  float64
+
+	// PartialThreshold and PartialPenalty are used to penalize the match
+	// that only contains a small part of the query. If the completeness is
+	// not bigger than the threshold, it will be multiplied by the penalty.
+	// If it's zero or negative, the default 0.5 will be used. To disable
+	// the penalty, set the penalty to 1.
+	PartialThreshold float64
+	PartialPenalty   float64
+}
+
+// Completeness returns the ratio of the matched trigrams in the query.
+func (s DefaultScorer) Completeness(m Match) float64 {
+	threshold, penalty := s.partialPenalty()
+	return index.Completeness(index.Match(m), threshold, penalty)
+}
+
+// partialPenalty returns the threshold and penalty for the partial
+// match, where the default is used for the unset ones.
+func (s DefaultScorer) partialPenalty() (threshold, penalty float64) {
+	threshold, penalty = s.PartialThreshold, s.PartialPenalty
+	if threshold <= 0 {
+		threshold = index.DefaultPartialThreshold
+	}
+	if penalty <= 0 {
+		penalty = index.DefaultPartialPenalty
+	}
+	return
+}
+
+// Compactness compares the mean gap between the matched positions with the
+// ideal gap. If the gap is bigger than the ideal, the compactness is reduced.
+func (s DefaultScorer) Compactness(m Match) float64 {
+	return index.Compactness(index.Match(m))
+}
+
+// Score returns the weighted product of completeness and compactness.
+func (s DefaultScorer) Score(m Match) float64 {
+	completeness := applyWeight(s.Completeness(m), s.CompletenessWeight)
+	compactness := applyWeight(s.Compactness(m), s.CompactnessWeight)
+	return completeness * compactness
+}
+
+// IDFScorer is the same as DefaultScorer, except the completeness is weighted
+// by the IDF of the trigrams. Common trigrams (e.g. the ones in "allah") are
+// less important than the rare ones, so the match that only contains the
+// common trigrams will get lower score.
+type IDFScorer struct {
+	DefaultScorer
+}
+
+// Completeness returns the ratio of the IDF of matched trigrams to the IDF of
+// all trigrams in the query.
+func (s IDFScorer) Completeness(m Match) float64 {
+	threshold, penalty := s.partialPenalty()
+	return index.WeightedCompleteness(index.Match(m), threshold, penalty)
+}
+
+// Score returns the weighted product of IDF-weighted completeness and
+// compactness.
+func (s IDFScorer) Score(m Match) float64 {
+	completeness := applyWeight(s.Completeness(m), s.CompletenessWeight)
+	compactness := applyWeight(s.Compactness(m), s.CompactnessWeight)
+	return completeness * compactness
+}
+
+// CoverageScorer multiplies the score from the base scorer by the coverage of
+// the match, i.e. the ratio of the match length to the document length. It
+// prefers the document that mostly consists of the query, which is useful for
+// corpus with long documents where the short one is usually more relevant.
+type CoverageScorer struct {
+	// Base is the scorer that used before the coverage is applied.
+	// If it's nil, DefaultScorer will be used.
+	Base Scorer
+
+	// Weight is the exponent that applied to the coverage. Smaller weight
+	// means the coverage is less important. If it's zero or negative,
+	// the default 0.2 will be used.
+	Weight float64
+}
+
+// Score returns the base score multiplied by the weighted coverage.
+func (s CoverageScorer) Score(m Match) float64 {
+	var base Scorer = DefaultScorer{}
+	if s.Base != nil {
+		base = s.Base
+	}
+
+	weight := s.Weight
+	if weight <= 0 {
+		weight = 0.2
+	}
+
+	coverage := 1.0
+	if m.DocumentLength > 0 {
+		coverage = min(1, float64(m.End-m.Start)/float64(m.DocumentLength))
+	}
+
+	return base.Score(m) * applyWeight(coverage, weight)
+}
+
+func applyWeight(score, weight float64) float64 {
+	if weight > 0 && weight != 1 {
+		return math.Pow(score, weight)
+	}
+	return score
+}
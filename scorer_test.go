@@ -0,0 +1,37 @@
+package lafzi
+
+import "testing"
+
+func TestScorerPartialPenalty(t *testing.T) {
+	// With the same IDF for every trigram, IDFScorer must
+	// give the same score as DefaultScorer.
+	matches := []Match{
+		{Tokens: []int{0, 1, 2, 3}, Positions: []int{0, 1, 2, 3}, QueryLength: 4, IDF: []float64{2, 2, 2, 2}},
+		{Tokens: []int{0, 1}, Positions: []int{0, 1}, QueryLength: 4, IDF: []float64{2, 2, 2, 2}},
+		{Tokens: []int{1}, Positions: []int{5}, QueryLength: 4, IDF: []float64{2, 2, 2, 2}},
+	}
+
+	scorers := []DefaultScorer{
+		{},
+		{PartialThreshold: 0.5, PartialPenalty: 0.5},
+		{PartialThreshold: 0.3, PartialPenalty: 0.8},
+		{PartialPenalty: 1},
+	}
+
+	for _, ds := range scorers {
+		for _, m := range matches {
+			want := ds.Score(m)
+			if got := (IDFScorer{ds}).Score(m); got != want {
+				t.Errorf("%+v: IDFScorer.Score(%v) = %v, want %v", ds, m.Tokens, got, want)
+			}
+		}
+	}
+
+	// The default threshold and penalty are 0.5
+	explicit := DefaultScorer{PartialThreshold: 0.5, PartialPenalty: 0.5}
+	for _, m := range matches {
+		if got, want := (DefaultScorer{}).Score(m), explicit.Score(m); got != want {
+			t.Errorf("default score of %v = %v, want %v", m.Tokens, got, want)
+		}
+	}
+}
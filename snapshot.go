@@ -0,0 +1,56 @@
+package lafzi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/hablullah/go-lafzi/internal/memory"
+)
+
+// ErrSnapshotUnsupported is returned when writing snapshot
+// of a storage that doesn't keep its indexes in memory.
+var ErrSnapshotUnsupported = errors.New("lafzi: snapshot is only supported by memory storage")
+
+// WriteSnapshot writes the documents and their reverse indexes into w, using
+// a compact and versioned binary format. It's only supported by the storage
+// that created by NewMemoryStorage or LoadSnapshot. The snapshot can be loaded
+// back using LoadSnapshot, so the documents don't need to be indexed again.
+func (st *Storage) WriteSnapshot(w io.Writer) error {
+	// Make sure storage is still open
+	if err := st.acquire(); err != nil {
+		return err
+	}
+	defer st.mu.RUnlock()
+
+	snapshotter, ok := st.idx.(interface{ WriteSnapshot(io.Writer) error })
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+
+	return snapshotter.WriteSnapshot(w)
+}
+
+// LoadSnapshot creates a new memory storage from the
+// snapshot that created by Storage.WriteSnapshot.
+func LoadSnapshot(r io.Reader) (*Storage, error) {
+	idx, err := memory.LoadSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStorage(idx), nil
+}
+
+// LoadSnapshotFS creates a new memory storage from the snapshot file in the
+// file system. It's useful for loading the snapshot that embedded using
+// embed.FS, so the indexes can be shipped within the binary.
+func LoadSnapshotFS(fsys fs.FS, name string) (*Storage, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadSnapshot(f)
+}